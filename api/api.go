@@ -2,6 +2,7 @@ package api
 
 import (
   "fmt"
+  "io/ioutil"
   "net/http"
   "strconv"
   "strings"
@@ -10,6 +11,17 @@ import (
 func HandleFunc(collection Collection, pathPrefix string) (func(http.ResponseWriter, *http.Request)) {
   return func(w http.ResponseWriter, r *http.Request) {
     relativePath := r.URL.Path[len(pathPrefix) - 1:]
+    if (r.Method == "GET" || r.Method == "POST") && strings.HasSuffix(relativePath, "/_search") { // elasticsearch compatible search
+      index := strings.Trim(relativePath[:len(relativePath) - 8], "/")
+      if index == "" {
+        index = "items"
+      }
+      if body, err := ioutil.ReadAll(r.Body); err == nil {
+        output := collection.Elastic(index, body)
+        SendJSONResponseStatus(w, ResponseStatus(output), output)
+        return
+      }
+    }
     if r.Method == "GET" {
       if relativePath == "/schema.json" { // schema
         SendJSONResponse(w, collection.Schema())
@@ -18,15 +30,58 @@ func HandleFunc(collection Collection, pathPrefix string) (func(http.ResponseWri
         SendJSONResponse(w, collection.SearchMeta())
         return
       } else if relativePath == "/search.json" { // search
-        SendJSONResponse(w, collection.Search(r.URL.Query()))
+        output := collection.Search(r.URL.Query())
+        SetLinkHeader(w, r, output)
+        SendJSONResponse(w, output)
+        return
+      } else if strings.HasPrefix(relativePath, "/odata/") { // odata v4 feed
+        w.Header().Set("OData-Version", "4.0")
+        switch relativePath {
+          case "/odata/":
+            SendJSONResponse(w, collection.ODataService())
+            return
+          case "/odata/$metadata":
+            metadata := collection.ODataMetadata()
+            SendTextResponse(w, &metadata, "application/xml")
+            return
+          case "/odata/Items":
+            output := collection.OData(r.URL.Query())
+            SendJSONResponseStatus(w, ODataStatus(output), output)
+            return
+        }
+      } else if relativePath == "/groupBy.json" { // aggregates per group
+        SendJSONResponse(w, collection.GroupBy(r.URL.Query()))
+        return
+      } else if relativePath == "/duplicates.json" { // near duplicate clusters
+        SendJSONResponse(w, collection.Duplicates(r.URL.Query()))
         return
+      } else if strings.HasSuffix(relativePath, "/similar.json") && len(relativePath) > 14 { // similar records
+        if index, err := strconv.Atoi(relativePath[1 : len(relativePath) - 13]); err == nil && index < collection.TotalItems() && index >= 0 {
+          output := collection.Similar(index, r.URL.Query())
+          SetLinkHeader(w, r, output)
+          SendJSONResponse(w, output)
+          return
+        }
       } else if strings.HasSuffix(relativePath, ".json") && len(relativePath) > 6 { // read operation
         if index, err := strconv.Atoi(relativePath[1 : len(relativePath) - 5]); err == nil && index < collection.TotalItems() && index >= 0 {
-          SendJSONResponse(w, collection.GetItem(index))
+          SendJSONResponse(w, collection.GetItem(index, r.URL.Query()))
           return
         }
       }
     }
+    if r.Method == "POST" && relativePath == "/search.json" { // search with a json body
+      if body, err := ioutil.ReadAll(r.Body); err == nil {
+        output := collection.SearchPost(r.URL.Query(), body)
+        SetLinkHeader(w, r, output)
+        SendJSONResponse(w, output)
+        return
+      }
+    } else if r.Method == "POST" && relativePath == "/sql" { // sql statement as body
+      if body, err := ioutil.ReadAll(r.Body); err == nil {
+        SendJSONResponse(w, collection.SQL(string(body)))
+        return
+      }
+    }
     SendJSONResponse(w, nil)
     return
   }
@@ -0,0 +1,45 @@
+package api
+
+import (
+  "net/http/httptest"
+  "reflect"
+  "testing"
+)
+
+// records the similar requests; other methods are not used
+type SimilarCollection struct {
+  Collection
+  Calls []int
+}
+
+func (collection * SimilarCollection) TotalItems() int {
+  return 3
+}
+
+func (collection * SimilarCollection) Similar(index int, query map[string][]string) interface{} {
+  collection.Calls = append(collection.Calls, index)
+  return map[string]interface{}{"results": []interface{}{}, "next": "abc"}
+}
+
+func TestSimilarRoute(t *testing.T) {
+  tests := []struct {
+    path string
+    calls []int
+    link string
+  }{
+    {"/items/1/similar.json", []int{1}, "</items/1/similar.json?cursor=abc>; rel=\"next\""},
+    {"/items/0/similar.json?limit=5", []int{0}, "</items/0/similar.json?cursor=abc>; rel=\"next\""},
+    {"/items/3/similar.json", nil, ""},
+    {"/items/-1/similar.json", nil, ""},
+    {"/items/x/similar.json", nil, ""},
+    {"/items/similar.json", nil, ""},
+  }
+  for _, test := range tests {
+    collection := &SimilarCollection{}
+    recorder := httptest.NewRecorder()
+    HandleFunc(collection, "/items/")(recorder, httptest.NewRequest("GET", test.path, nil))
+    if !reflect.DeepEqual(collection.Calls, test.calls) || recorder.Header().Get("Link") != test.link {
+      t.Errorf("GET %s called Similar with %v, Link %q, want %v, %q", test.path, collection.Calls, recorder.Header().Get("Link"), test.calls, test.link)
+    }
+  }
+}
@@ -5,7 +5,16 @@ package api
 type Collection interface {
   Schema() interface{}
   SearchMeta() interface{}
-  GetItem(int) interface{}
+  GetItem(int, map[string][]string) interface{}
   TotalItems() int
   Search(map[string][]string) interface{}
+  SearchPost(map[string][]string, []byte) interface{}
+  Similar(int, map[string][]string) interface{}
+  Duplicates(map[string][]string) interface{}
+  GroupBy(map[string][]string) interface{}
+  SQL(string) interface{}
+  OData(map[string][]string) interface{}
+  ODataService() interface{}
+  ODataMetadata() []byte
+  Elastic(string, []byte) interface{}
 }
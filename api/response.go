@@ -3,20 +3,46 @@ package api
 import (
   "fmt"
   "net/http"
+  "net/url"
   "encoding/json"
+  "strings"
 )
 
 func SendJSONResponse(w http.ResponseWriter, o interface{}) {
+  SendJSONResponseStatus(w, http.StatusOK, o)
+}
+
+func SendJSONResponseStatus(w http.ResponseWriter, status int, o interface{}) {
   //js, err := json.Marshal(o)
   js, err := json.MarshalIndent(o, "", "  ")
   if (err != nil) {
     fmt.Println("JSON ENCODING ERROR", err)
   }
   w.Header().Set("Content-Type", "application/json")
-  w.WriteHeader(http.StatusOK)
+  w.WriteHeader(status)
   w.Write(js)
 }
 
+// the "status" of an elasticsearch style error body, 200 otherwise
+func ResponseStatus(o interface{}) int {
+  if output, isMap := o.(map[string]interface{}); isMap {
+    if status, has := output["status"].(int); has {
+      return status
+    }
+  }
+  return http.StatusOK
+}
+
+// 400 for an odata error body, 200 otherwise
+func ODataStatus(o interface{}) int {
+  if output, isMap := o.(map[string]interface{}); isMap {
+    if _, has := output["error"]; has {
+      return http.StatusBadRequest
+    }
+  }
+  return http.StatusOK
+}
+
 func SendTextResponse(w http.ResponseWriter, o * []byte, mime string) (int, error) {
   // js, err := json.Marshal(o)
   w.Header().Set("Content-Type", mime)
@@ -24,3 +50,20 @@ func SendTextResponse(w http.ResponseWriter, o * []byte, mime string) (int, erro
   w.Write(*o)
   return 0, nil
 }
+
+// RFC 8288 links to the next and previous pages of a search
+func SetLinkHeader(w http.ResponseWriter, r * http.Request, o interface{}) {
+  output, isMap := o.(map[string]interface{})
+  if !isMap {
+    return
+  }
+  links := make([]string, 0, 2)
+  for _, rel := range []string{"next", "prev"} {
+    if token, has := output[rel].(string); has {
+      links = append(links, fmt.Sprintf("<%s?cursor=%s>; rel=\"%s\"", r.URL.Path, url.QueryEscape(token), rel))
+    }
+  }
+  if len(links) > 0 {
+    w.Header().Set("Link", strings.Join(links, ", "))
+  }
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+  "net/http"
+  "net/http/httptest"
+  "testing"
+)
+
+func TestResponseStatus(t *testing.T) {
+  tests := []struct {
+    output interface{}
+    status int
+  }{
+    {map[string]interface{}{"error": map[string]interface{}{"type": "parsing_exception"}, "status": 400}, http.StatusBadRequest},
+    {map[string]interface{}{"hits": map[string]interface{}{}}, http.StatusOK},
+    {map[string]interface{}{"status": "red"}, http.StatusOK},
+    {[]int{1, 2}, http.StatusOK},
+    {nil, http.StatusOK},
+  }
+  for _, test := range tests {
+    recorder := httptest.NewRecorder()
+    SendJSONResponseStatus(recorder, ResponseStatus(test.output), test.output)
+    if recorder.Code != test.status {
+      t.Errorf("%v sent with status %d, want %d", test.output, recorder.Code, test.status)
+    }
+    if contentType := recorder.Header().Get("Content-Type"); contentType != "application/json" {
+      t.Errorf("%v sent as %s", test.output, contentType)
+    }
+  }
+}
+
+func TestODataStatus(t *testing.T) {
+  tests := []struct {
+    output interface{}
+    status int
+  }{
+    {map[string]interface{}{"error": map[string]interface{}{"code": "BadRequest", "message": "$top: expected a whole number"}}, http.StatusBadRequest},
+    {map[string]interface{}{"@odata.context": "$metadata#Items", "value": []map[string]interface{}{}}, http.StatusOK},
+    {nil, http.StatusOK},
+  }
+  for _, test := range tests {
+    recorder := httptest.NewRecorder()
+    SendJSONResponseStatus(recorder, ODataStatus(test.output), test.output)
+    if recorder.Code != test.status {
+      t.Errorf("%v sent with status %d, want %d", test.output, recorder.Code, test.status)
+    }
+  }
+}
@@ -0,0 +1,220 @@
+package index
+
+import (
+  "fmt"
+  "math"
+  "strconv"
+  "strings"
+)
+
+var DEFAULT_PERCENTILES = []float64{1, 5, 25, 50, 75, 95, 99}
+
+// histograms with more buckets are refused
+const MAX_HISTOGRAM_BUCKETS int = 10000
+
+// aggs=stats:price,percentiles:price:50,90,99,histogram:price:10
+// stats, count, sum, min, max, avg or stddev, percentiles (default
+// DEFAULT_PERCENTILES) or histogram with an interval, over a number field
+type SearchAggregation struct {
+  Function string
+  Field string
+  Arguments []float64
+}
+
+func (aggregation SearchAggregation) Name() string {
+  return aggregation.Function + "(" + aggregation.Field + ")"
+}
+
+func ParseAggregations(value string, schema * Schema) ([]SearchAggregation, error) {
+  aggregations := make([]SearchAggregation, 0)
+  for _, item := range strings.Split(value, ",") {
+    item = strings.TrimSpace(item)
+    if item == "" {
+      continue
+    }
+    // further percentiles of the aggregation before
+    if last := len(aggregations) - 1; last >= 0 && aggregations[last].Function == "percentiles" {
+      if number, err := strconv.ParseFloat(item, 64); err == nil {
+        aggregations[last].Arguments = append(aggregations[last].Arguments, number)
+        continue
+      }
+    }
+    parts := strings.SplitN(item, ":", 3)
+    if len(parts) < 2 {
+      return nil, fmt.Errorf("aggregation '%s' should be function:field, e.g. stats:price", item)
+    }
+    aggregation := SearchAggregation{Function: parts[0], Field: parts[1]}
+    if len(parts) > 2 {
+      if aggregation.Function != "percentiles" && aggregation.Function != "histogram" {
+        return nil, fmt.Errorf("%s takes no arguments", aggregation.Function)
+      }
+      number, err := strconv.ParseFloat(parts[2], 64)
+      if err != nil {
+        return nil, fmt.Errorf("%s argument '%s' is not a number", aggregation.Function, parts[2])
+      }
+      aggregation.Arguments = append(aggregation.Arguments, number)
+    }
+    aggregations = append(aggregations, aggregation)
+  }
+  return aggregations, CheckAggregations(aggregations, schema)
+}
+
+// checks functions, fields and arguments, and fills in default percentiles
+func CheckAggregations(aggregations []SearchAggregation, schema * Schema) error {
+  for i, aggregation := range aggregations {
+    switch aggregation.Function {
+      case "stats", "percentiles", "histogram":
+        break;
+      default:
+        if err := (Aggregate{Function: aggregation.Function, Field: aggregation.Field}).Check(schema); err != nil {
+          return err
+        }
+        if len(aggregation.Arguments) > 0 {
+          return fmt.Errorf("%s takes no arguments", aggregation.Function)
+        }
+    }
+    fieldData, has := schema.Properties[aggregation.Field]
+    if !has {
+      return fmt.Errorf("field '%s' does not exist", aggregation.Field)
+    }
+    if fieldData.Type != "number" {
+      return fmt.Errorf("%s needs a number field; '%s' is %s", aggregation.Function, aggregation.Field, fieldData.Type)
+    }
+    for _, other := range aggregations[:i] {
+      if other.Name() == aggregation.Name() {
+        return fmt.Errorf("aggregation '%s' is given twice", aggregation.Name())
+      }
+    }
+    switch aggregation.Function {
+      case "stats":
+        if len(aggregation.Arguments) > 0 {
+          return fmt.Errorf("stats takes no arguments")
+        }
+        break;
+      case "percentiles":
+        if len(aggregation.Arguments) == 0 {
+          aggregations[i].Arguments = DEFAULT_PERCENTILES
+        }
+        for _, percent := range aggregation.Arguments {
+          if percent < 0 || percent > 100 {
+            return fmt.Errorf("percentile '%s' is not between 0 and 100", strconv.FormatFloat(percent, 'f', -1, 64))
+          }
+        }
+        break;
+      case "histogram":
+        if len(aggregation.Arguments) != 1 || !(aggregation.Arguments[0] > 0) {
+          return fmt.Errorf("histogram needs a positive interval, e.g. histogram:%s:10", aggregation.Field)
+        }
+        break;
+    }
+  }
+  return nil
+}
+
+// the aggs parameter, then those of a search body
+func (search * Search) Aggregations(request * SearchRequest, schema * Schema, results SearchResults) map[string]interface{} {
+  aggregations, err := ParseAggregations(strings.Join(request.Queries["aggs"], ","), schema)
+  if err == nil {
+    aggregations = append(aggregations, request.Aggs...)
+    err = CheckAggregations(aggregations, schema)
+  }
+  if err != nil {
+    request.Errors = append(request.Errors, err.Error())
+    return nil
+  }
+  output := make(map[string]interface{}, len(aggregations))
+  for _, aggregation := range aggregations {
+    value, err := aggregation.Compute(schema, results)
+    if err != nil {
+      request.Errors = append(request.Errors, err.Error())
+      continue
+    }
+    output[aggregation.Name()] = value
+  }
+  return output
+}
+
+// works on counts of dictionary codes; number dictionaries are sorted, so
+// percentiles are exact
+func (aggregation SearchAggregation) Compute(schema * Schema, results SearchResults) (interface{}, error) {
+  fieldData := schema.Properties[aggregation.Field]
+  counts := make([]int, len(fieldData.UniqueValues))
+  var state AggregateState
+  for _, x := range results {
+    if code := fieldData.ValueIndex[x.Item]; code != -1 {
+      counts[code]++
+    }
+  }
+  for code, count := range counts {
+    if count > 0 {
+      state.AddCount(fieldData.UniqueValues[code].(float64), count)
+    }
+  }
+  switch aggregation.Function {
+    case "stats":
+      stats := make(map[string]interface{}, len(AGGREGATE_FUNCTIONS))
+      for _, function := range AGGREGATE_FUNCTIONS {
+        stats[function] = state.Value(function)
+      }
+      return stats, nil
+    case "percentiles":
+      return Percentiles(fieldData.UniqueValues, counts, state.Count, aggregation.Arguments), nil
+    case "histogram":
+      return Histogram(fieldData.UniqueValues, counts, state, aggregation.Arguments[0])
+  }
+  return state.Value(aggregation.Function), nil
+}
+
+// linear interpolation between the closest ranks; nil without values
+func Percentiles(values []interface{}, counts []int, total int, percents []float64) map[string]interface{} {
+  output := make(map[string]interface{}, len(percents))
+  for _, percent := range percents {
+    key := strconv.FormatFloat(percent, 'f', -1, 64)
+    if total == 0 {
+      output[key] = nil
+      continue
+    }
+    rank := percent / 100 * float64(total - 1)
+    low, high := math.NaN(), math.NaN()
+    seen := 0
+    for code, count := range counts {
+      if count == 0 {
+        continue
+      }
+      seen += count
+      if math.IsNaN(low) && float64(seen - 1) >= math.Floor(rank) {
+        low = values[code].(float64)
+      }
+      if float64(seen - 1) >= math.Ceil(rank) {
+        high = values[code].(float64)
+        break
+      }
+    }
+    output[key] = low + (high - low) * (rank - math.Floor(rank))
+  }
+  return output
+}
+
+// buckets of interval width from the lowest value to the highest, empty ones
+// included; keys are the lower bounds
+func Histogram(values []interface{}, counts []int, state AggregateState, interval float64) ([]map[string]interface{}, error) {
+  buckets := make([]map[string]interface{}, 0)
+  if state.Count == 0 {
+    return buckets, nil
+  }
+  first := math.Floor(state.Min / interval)
+  size := math.Floor(state.Max / interval) - first + 1
+  if size > float64(MAX_HISTOGRAM_BUCKETS) {
+    return nil, fmt.Errorf("histogram interval %s gives more than %d buckets", strconv.FormatFloat(interval, 'f', -1, 64), MAX_HISTOGRAM_BUCKETS)
+  }
+  totals := make([]int, int(size))
+  for code, count := range counts {
+    if count > 0 {
+      totals[int(math.Floor(values[code].(float64) / interval) - first)] += count
+    }
+  }
+  for i, count := range totals {
+    buckets = append(buckets, map[string]interface{}{"key": (first + float64(i)) * interval, "count": count})
+  }
+  return buckets, nil
+}
@@ -0,0 +1,137 @@
+package index
+
+import (
+  "net/url"
+  "reflect"
+  "testing"
+)
+
+type Values map[string]interface{}
+
+func TestParseAggregations(t *testing.T) {
+  schema := FixtureCollection().schema
+  tests := []struct {
+    value string
+    want []SearchAggregation
+    err string
+  }{
+    {"stats:price", []SearchAggregation{{Function: "stats", Field: "price"}}, ""},
+    {"avg:price, max:popularity", []SearchAggregation{{Function: "avg", Field: "price"}, {Function: "max", Field: "popularity"}}, ""},
+    {"percentiles:price", []SearchAggregation{{Function: "percentiles", Field: "price", Arguments: []float64{1, 5, 25, 50, 75, 95, 99}}}, ""},
+    {"percentiles:price:50,90,99.9,histogram:price:10", []SearchAggregation{{Function: "percentiles", Field: "price", Arguments: []float64{50, 90, 99.9}}, {Function: "histogram", Field: "price", Arguments: []float64{10}}}, ""},
+    {"percentiles:price:0,100", []SearchAggregation{{Function: "percentiles", Field: "price", Arguments: []float64{0, 100}}}, ""},
+    {"", []SearchAggregation{}, ""},
+    {"price", nil, "aggregation 'price' should be function:field, e.g. stats:price"},
+    {"median:price", nil, "aggregate 'median' is not supported"},
+    {"stats:nope", nil, "field 'nope' does not exist"},
+    {"sum:color", nil, "sum needs a number field; 'color' is string"},
+    {"count:color", nil, "count needs a number field; 'color' is string"},
+    {"stats:price:5", nil, "stats takes no arguments"},
+    {"percentiles:price:x", nil, "percentiles argument 'x' is not a number"},
+    {"percentiles:price:50,101", nil, "percentile '101' is not between 0 and 100"},
+    {"percentiles:price:-1", nil, "percentile '-1' is not between 0 and 100"},
+    {"histogram:price", nil, "histogram needs a positive interval, e.g. histogram:price:10"},
+    {"histogram:price:0", nil, "histogram needs a positive interval, e.g. histogram:price:10"},
+    {"histogram:price:-5", nil, "histogram needs a positive interval, e.g. histogram:price:10"},
+    {"avg:price,avg:price", nil, "aggregation 'avg(price)' is given twice"},
+  }
+  for _, test := range tests {
+    aggregations, err := ParseAggregations(test.value, schema)
+    if err != nil {
+      if err.Error() != test.err {
+        t.Errorf("ParseAggregations(%q) error = %v, want %q", test.value, err, test.err)
+      }
+    } else if test.err != "" || !reflect.DeepEqual(aggregations, test.want) {
+      t.Errorf("ParseAggregations(%q) = %+v, want %+v, error %q", test.value, aggregations, test.want, test.err)
+    }
+  }
+}
+
+func TestPercentiles(t *testing.T) {
+  tests := []struct {
+    values []interface{}
+    counts []int
+    percents []float64
+    want Values
+  }{
+    {[]interface{}{1.0, 2.0, 3.0, 4.0}, []int{1, 1, 1, 1}, []float64{0, 25, 50, 100}, Values{"0": 1.0, "100": 4.0, "25": 1.75, "50": 2.5}},
+    {[]interface{}{10.0, 20.0}, []int{3, 1}, []float64{50, 90}, Values{"50": 10.0, "90": 17.0}},
+    {[]interface{}{5.0}, []int{1}, []float64{0, 50, 99}, Values{"0": 5.0, "50": 5.0, "99": 5.0}},
+    // values without records are skipped
+    {[]interface{}{1.0, 2.0, 3.0}, []int{0, 2, 0}, []float64{1, 99}, Values{"1": 2.0, "99": 2.0}},
+    {[]interface{}{-2.0, 0.0, 2.0}, []int{1, 0, 1}, []float64{50}, Values{"50": 0.0}},
+    // no records
+    {[]interface{}{1.0, 2.0}, []int{0, 0}, []float64{50}, Values{"50": nil}},
+    {[]interface{}{}, []int{}, []float64{50}, Values{"50": nil}},
+  }
+  for _, test := range tests {
+    total := 0
+    for _, count := range test.counts {
+      total += count
+    }
+    if got := Values(Percentiles(test.values, test.counts, total, test.percents)); !reflect.DeepEqual(got, test.want) {
+      t.Errorf("Percentiles(%v, %v, %v) = %v, want %v", test.values, test.counts, test.percents, got, test.want)
+    }
+  }
+}
+
+func TestHistogram(t *testing.T) {
+  tests := []struct {
+    values []interface{}
+    counts []int
+    interval float64
+    want Buckets
+    err string
+  }{
+    {[]interface{}{1.0, 4.0, 12.0}, []int{1, 2, 1}, 5, Buckets{{"count": 3, "key": 0.0}, {"count": 0, "key": 5.0}, {"count": 1, "key": 10.0}}, ""},
+    // a value on a bucket bound is in the bucket that starts at it
+    {[]interface{}{5.0, 10.0}, []int{1, 1}, 5, Buckets{{"count": 1, "key": 5.0}, {"count": 1, "key": 10.0}}, ""},
+    {[]interface{}{-3.0, 2.0}, []int{1, 1}, 2, Buckets{{"count": 1, "key": -4.0}, {"count": 0, "key": -2.0}, {"count": 0, "key": 0.0}, {"count": 1, "key": 2.0}}, ""},
+    {[]interface{}{0.25, 0.5}, []int{1, 1}, 0.5, Buckets{{"count": 1, "key": 0.0}, {"count": 1, "key": 0.5}}, ""},
+    {[]interface{}{7.0}, []int{4}, 100, Buckets{{"count": 4, "key": 0.0}}, ""},
+    {[]interface{}{1.0, 2.0}, []int{0, 0}, 1, Buckets{}, ""},
+    {[]interface{}{0.0, 1e9}, []int{1, 1}, 1, nil, "histogram interval 1 gives more than 10000 buckets"},
+  }
+  for _, test := range tests {
+    var state AggregateState
+    for code, count := range test.counts {
+      if count > 0 {
+        state.AddCount(test.values[code].(float64), count)
+      }
+    }
+    buckets, err := Histogram(test.values, test.counts, state, test.interval)
+    if err != nil {
+      if err.Error() != test.err {
+        t.Errorf("Histogram(%v, %v, %v) error = %v, want %q", test.values, test.counts, test.interval, err, test.err)
+      }
+    } else if test.err != "" || !reflect.DeepEqual(Buckets(buckets), test.want) {
+      t.Errorf("Histogram(%v, %v, %v) = %v, want %v, error %q", test.values, test.counts, test.interval, buckets, test.want, test.err)
+    }
+  }
+}
+
+func TestSearchAggregations(t *testing.T) {
+  collection := FixtureCollection()
+  tests := []struct {
+    queries url.Values
+    want map[string]interface{}
+  }{
+    {url.Values{"popularity": {"lessThan:4"}, "aggs": {"stats:popularity,percentiles:popularity:50"}}, map[string]interface{}{
+      "percentiles(popularity)": map[string]interface{}{"50": 1.5},
+      "stats(popularity)": map[string]interface{}{"avg": 1.5, "count": 4, "max": 3.0, "min": 0.0, "stddev": 1.118033988749895, "sum": 6.0},
+    }},
+    // records without a value are left out
+    {url.Values{"popularity": {"lessThan:8"}, "aggs": {"count:price,sum:price"}}, map[string]interface{}{"count(price)": 6, "sum(price)": 21.0}},
+    {url.Values{"popularity": {"greaterThan:1000"}, "aggs": {"stats:price,percentiles:price:50,histogram:price:10"}}, map[string]interface{}{
+      "histogram(price)": []map[string]interface{}{},
+      "percentiles(price)": map[string]interface{}{"50": nil},
+      "stats(price)": map[string]interface{}{"avg": nil, "count": 0, "max": nil, "min": nil, "stddev": nil, "sum": nil},
+    }},
+  }
+  for _, test := range tests {
+    output := collection.search.Search(test.queries, collection.schema)
+    if !reflect.DeepEqual(output["aggregations"], test.want) || output["errors"] != nil {
+      t.Errorf("%v: aggregations = %v, errors = %v, want %v", test.queries, output["aggregations"], output["errors"], test.want)
+    }
+  }
+}
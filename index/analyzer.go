@@ -0,0 +1,173 @@
+package index
+
+import (
+  "strings"
+  "unicode"
+)
+
+// turns text into search terms; same analyzer must be used at index and query time
+
+type Analyzer struct {
+  FoldCase bool
+  FoldAccents bool
+  Language * Language
+}
+
+// accented letters grouped by what they fold to
+var accentFoldGroups = map[string]string{
+  "A": "ÀÁÂÃÄÅĀĂĄǍǞǠǺȀȂȦḀẠẢẤẦẨẪẬẮẰẲẴẶ",
+  "a": "àáâãäåāăąǎǟǡǻȁȃȧḁạảấầẩẫậắằẳẵặ",
+  "AE": "ÆǢǼ",
+  "ae": "æǣǽ",
+  "B": "ḂḄḆ",
+  "b": "ḃḅḇ",
+  "C": "ÇĆĈĊČḈ",
+  "c": "çćĉċčḉ",
+  "D": "ĎḊḌḎḐḒĐÐ",
+  "d": "ďḋḍḏḑḓđð",
+  "E": "ÈÉÊËĒĔĖĘĚȄȆȨḔḖḘḚḜẸẺẼẾỀỂỄỆ",
+  "e": "èéêëēĕėęěȅȇȩḕḗḙḛḝẹẻẽếềểễệ",
+  "F": "Ḟ",
+  "f": "ḟ",
+  "G": "ĜĞĠĢǦǴḠ",
+  "g": "ĝğġģǧǵḡ",
+  "H": "ĤȞḢḤḦḨḪĦ",
+  "h": "ĥȟḣḥḧḩḫẖħ",
+  "I": "ÌÍÎÏĨĪĬĮİǏȈȊḬḮỈỊ",
+  "i": "ìíîïĩīĭįǐȉȋḭḯỉịı",
+  "J": "Ĵ",
+  "j": "ĵǰ",
+  "K": "ĶǨḰḲḴ",
+  "k": "ķǩḱḳḵ",
+  "L": "ĹĻĽḶḸḺḼŁ",
+  "l": "ĺļľḷḹḻḽł",
+  "M": "ḾṀṂ",
+  "m": "ḿṁṃ",
+  "N": "ÑŃŅŇǸṄṆṈṊ",
+  "n": "ñńņňǹṅṇṉṋ",
+  "O": "ÒÓÔÕÖŌŎŐƠǑǪǬȌȎȪȬȮȰṌṎṐṒỌỎỐỒỔỖỘỚỜỞỠỢØǾ",
+  "o": "òóôõöōŏőơǒǫǭȍȏȫȭȯȱṍṏṑṓọỏốồổỗộớờởỡợøǿ",
+  "OE": "Œ",
+  "oe": "œ",
+  "P": "ṔṖ",
+  "p": "ṕṗ",
+  "R": "ŔŖŘȐȒṘṚṜṞ",
+  "r": "ŕŗřȑȓṙṛṝṟ",
+  "S": "ŚŜŞŠȘṠṢṤṦṨ",
+  "s": "śŝşšșṡṣṥṧṩ",
+  "ss": "ß",
+  "T": "ŢŤȚṪṬṮṰŦ",
+  "t": "ţťțṫṭṯṱẗŧ",
+  "TH": "Þ",
+  "th": "þ",
+  "U": "ÙÚÛÜŨŪŬŮŰŲƯǓǕǗǙǛȔȖṲṴṶṸṺỤỦỨỪỬỮỰ",
+  "u": "ùúûüũūŭůűųưǔǖǘǚǜȕȗṳṵṷṹṻụủứừửữự",
+  "V": "ṼṾ",
+  "v": "ṽṿ",
+  "W": "ŴẀẂẄẆẈ",
+  "w": "ŵẁẃẅẇẉẘ",
+  "X": "ẊẌ",
+  "x": "ẋẍ",
+  "Y": "ÝŶŸȲẎỲỴỶỸ",
+  "y": "ýÿŷȳẏẙỳỵỷỹ",
+  "Z": "ŹŻŽẐẒẔ",
+  "z": "źżžẑẓẕ",
+  "Α": "Ά",
+  "α": "ά",
+  "Ε": "Έ",
+  "ε": "έ",
+  "Η": "Ή",
+  "η": "ή",
+  "Ι": "ΊΪ",
+  "ι": "ΐίϊ",
+  "Ο": "Ό",
+  "ο": "ό",
+  "Υ": "ΎΫ",
+  "υ": "ΰϋύ",
+  "Ω": "Ώ",
+  "ω": "ώ",
+  "Г": "Ѓ",
+  "г": "ѓ",
+  "Е": "ЀЁ",
+  "е": "ѐё",
+  "И": "ЍЙ",
+  "и": "йѝ",
+  "К": "Ќ",
+  "к": "ќ",
+  "У": "Ў",
+  "у": "ў",
+  "І": "Ї",
+  "і": "ї",
+}
+
+var accentFolds map[rune]string
+
+func init() {
+  accentFolds = make(map[rune]string)
+  for folded, accented := range accentFoldGroups {
+    for _, item := range accented {
+      accentFolds[item] = folded
+    }
+  }
+}
+
+func FoldAccents(str string) string {
+  var output strings.Builder
+  for _, item := range str {
+    if folded, has := accentFolds[item]; has {
+      output.WriteString(folded)
+    } else if !unicode.Is(unicode.Mn, item) {
+      output.WriteRune(item)
+    }
+  }
+  return output.String()
+}
+
+func (analyzer Analyzer) Fold(str string) string {
+  if analyzer.FoldCase {
+    str = strings.ToLower(str)
+  }
+  if analyzer.FoldAccents {
+    str = FoldAccents(str)
+  }
+  return str
+}
+
+// stemmed search term for a word; false for stop words
+func (analyzer Analyzer) Term(word string) (string, bool) {
+  language := analyzer.Language
+  word = language.StripElision(word)
+  if language.StopWords[strings.ToLower(word)] {
+    return EMPTY, false
+  }
+  // folded before stemming so accented and plain spellings stem alike
+  word = analyzer.Fold(word)
+  return language.Stem(word), true
+}
+
+// tokens keep offsets into the original string; text is replaced by the term
+func (analyzer Analyzer) Analyze(str string) []Token {
+  tokens := Tokenize(str)
+  output := tokens[:0]
+  for _, token := range tokens {
+    if IsWord(token.Text) {
+      if term, keep := analyzer.Term(token.Text); keep {
+        token.Text = term
+        output = append(output, token)
+      }
+    } else {
+      token.Text = analyzer.Fold(token.Text)
+      output = append(output, token)
+    }
+  }
+  return output
+}
+
+func (analyzer Analyzer) LexAndStem(str string) []string {
+  tokens := analyzer.Analyze(str)
+  output := make([]string, len(tokens))
+  for i, token := range tokens {
+    output[i] = token.Text
+  }
+  return output
+}
@@ -0,0 +1,56 @@
+package index
+
+import (
+  "testing"
+)
+
+func TestFoldAccents(t *testing.T) {
+  tests := []struct {
+    str string
+    want string
+  }{
+    {"Crème Brûlée", "Creme Brulee"},
+    {"Æsir", "AEsir"},
+    {"straße", "strasse"},
+    {"e\u0301te\u0301", "ete"}, // combining marks
+    {"Ёлка", "Елка"},
+    {"plain", "plain"},
+    {"", ""},
+  }
+  for _, test := range tests {
+    if got := FoldAccents(test.str); got != test.want {
+      t.Errorf("FoldAccents(%q) = %q, want %q", test.str, got, test.want)
+    }
+  }
+}
+
+// accents are folded before stemming, so accented and plain spellings give
+// the same term
+func TestAnalyzerTerm(t *testing.T) {
+  tests := []struct {
+    language string
+    foldAccents bool
+    word string
+    want string
+    ok bool
+  }{
+    {"german", true, "Häuser", "haus", true},
+    {"german", true, "Hauser", "haus", true},
+    {"german", true, "Straße", "strass", true},
+    {"german", true, "Strasse", "strass", true},
+    {"german", true, "Café", "caf", true},
+    {"german", true, "CAFÉS", "caf", true},
+    {"german", true, "Über", "", false},
+    {"none", false, "Café", "café", true},
+    {"none", true, "Café", "cafe", true},
+    {"none", true, "Schön", "schon", true},
+    {"english", true, "The", "", false},
+    {"french", true, "l'été", "", false},
+  }
+  for _, test := range tests {
+    analyzer := Analyzer{FoldCase: true, FoldAccents: test.foldAccents, Language: LookupLanguage(test.language)}
+    if got, ok := analyzer.Term(test.word); got != test.want || ok != test.ok {
+      t.Errorf("%s Term(%q) = %q, %v, want %q, %v", test.language, test.word, got, ok, test.want, test.ok)
+    }
+  }
+}
@@ -5,6 +5,11 @@ package index
 type Collection struct {
   schema * Schema
   search * Search
+  ready chan bool // closed once indexed
+}
+
+func (collection Collection) Wait() {
+  <-collection.ready
 }
 
 func (collection Collection) Schema() interface{} {
@@ -15,8 +20,8 @@ func (collection Collection) SearchMeta() interface{} {
   return collection.search
 }
 
-func (collection Collection) GetItem(index int) interface{} {
-  return collection.schema.GetItem(index)
+func (collection Collection) GetItem(index int, query map[string][]string) interface{} {
+  return collection.schema.GetProjectedItem(index, query)
 }
 
 func (collection Collection) TotalItems() int {
@@ -26,3 +31,39 @@ func (collection Collection) TotalItems() int {
 func (collection Collection) Search(query map[string][]string) interface{} {
   return collection.search.Search(query, collection.schema)
 }
+
+func (collection Collection) SearchPost(query map[string][]string, body []byte) interface{} {
+  return collection.search.SearchPost(query, body, collection.schema)
+}
+
+func (collection Collection) Similar(index int, query map[string][]string) interface{} {
+  return collection.search.Similar(index, query, collection.schema)
+}
+
+func (collection Collection) Duplicates(query map[string][]string) interface{} {
+  return collection.search.FindDuplicates(query, collection.schema)
+}
+
+func (collection Collection) GroupBy(query map[string][]string) interface{} {
+  return collection.search.Groups(query, collection.schema)
+}
+
+func (collection Collection) SQL(statement string) interface{} {
+  return collection.search.SQL(statement, collection.schema)
+}
+
+func (collection Collection) OData(query map[string][]string) interface{} {
+  return collection.search.OData(query, collection.schema)
+}
+
+func (collection Collection) ODataService() interface{} {
+  return ODataService()
+}
+
+func (collection Collection) ODataMetadata() []byte {
+  return ODataMetadata(collection.schema)
+}
+
+func (collection Collection) Elastic(index string, body []byte) interface{} {
+  return collection.search.Elastic(index, body, collection.schema)
+}
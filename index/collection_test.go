@@ -0,0 +1,37 @@
+package index
+
+import (
+  "fmt"
+  "sync"
+)
+
+var fixture Collection
+var fixtureOnce sync.Once
+
+// 400 records shared by the tests: title (full text), color, brand, price
+// (missing every seventh record), popularity and flag
+func FixtureCollection() Collection {
+  fixtureOnce.Do(func(){
+    count := 400
+    data := make(map[string][]interface{})
+    for _, field := range []string{"title", "color", "brand", "price", "popularity", "flag"} {
+      data[field] = make([]interface{}, count)
+    }
+    for i := 0; i < count; i++ {
+      data["title"][i] = fmt.Sprintf("Item %d of the data loader", i)
+      if i % 3 == 0 {
+        data["title"][i] = fmt.Sprintf("Television set %d with remote", i)
+      }
+      data["color"][i] = []string{"red", "blue", "green"}[i % 3]
+      data["brand"][i] = []string{"acme", "globex", "initech", "umbrella"}[i % 4]
+      if i % 7 != 0 {
+        data["price"][i] = float64(i % 50)
+      }
+      data["popularity"][i] = float64(i)
+      data["flag"][i] = i % 2 == 0
+    }
+    fixture = Index(data, Options{FoldCase: true})
+    fixture.Wait()
+  })
+  return fixture
+}
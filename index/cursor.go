@@ -0,0 +1,200 @@
+package index
+
+import (
+  "encoding/base64"
+  "fmt"
+  "net/url"
+  "strconv"
+  "strings"
+  "sync"
+  "time"
+)
+
+// a sorted result set and what is needed to show pages of it. Only the first
+// Window results are kept; pages past them are reached with offset.
+type Cursor struct {
+  Request * SearchRequest
+  Results SearchResults
+  Total int
+  Collapsed map[int]int
+  Projection * Projection
+  Created time.Time
+}
+
+// copies the first window results so the full result set is not kept alive
+func NewCursor(request * SearchRequest, results SearchResults, collapsed map[int]int, projection * Projection, window int) * Cursor {
+  if window > len(results) {
+    window = len(results)
+  }
+  cursor := &Cursor{Request: request, Results: append(SearchResults(nil), results[:window]...), Total: len(results), Projection: projection}
+  if collapsed != nil {
+    cursor.Collapsed = make(map[int]int, window)
+    for _, x := range cursor.Results {
+      cursor.Collapsed[x.Item] = collapsed[x.Item]
+    }
+  }
+  return cursor
+}
+
+// whether the page from offset is kept in full
+func (cursor * Cursor) Covers(offset int, limit int) bool {
+  return len(cursor.Results) == cursor.Total || offset + limit <= len(cursor.Results)
+}
+
+// least recently used cursors go first once there are Size of them; cursors
+// older than TTL expire
+type CursorCache struct {
+  Lock sync.Mutex
+  Size int
+  TTL time.Duration
+  Cursors map[string]*Cursor
+  Order []string // least recently used first
+  Count int
+}
+
+func (cache * CursorCache) Put(cursor * Cursor) string {
+  cache.Lock.Lock()
+  defer cache.Lock.Unlock()
+  if cache.Cursors == nil {
+    cache.Cursors = make(map[string]*Cursor)
+  }
+  now := time.Now()
+  order := cache.Order[:0]
+  for _, id := range cache.Order {
+    if cache.Expired(cache.Cursors[id], now) {
+      delete(cache.Cursors, id)
+    } else {
+      order = append(order, id)
+    }
+  }
+  cache.Order = order
+  cache.Count += 1
+  id := strconv.FormatInt(int64(cache.Count), 36)
+  cursor.Created = now
+  cache.Cursors[id] = cursor
+  cache.Order = append(cache.Order, id)
+  for len(cache.Order) > cache.Size && len(cache.Order) > 1 {
+    delete(cache.Cursors, cache.Order[0])
+    cache.Order = cache.Order[1:]
+  }
+  return id
+}
+
+func (cache * CursorCache) Get(id string) (* Cursor, bool) {
+  cache.Lock.Lock()
+  defer cache.Lock.Unlock()
+  cursor, has := cache.Cursors[id]
+  for i, other := range cache.Order {
+    if other == id {
+      cache.Order = append(cache.Order[:i:i], cache.Order[i + 1:]...)
+      break
+    }
+  }
+  if !has || cache.Expired(cursor, time.Now()) {
+    delete(cache.Cursors, id)
+    return nil, false
+  }
+  cache.Order = append(cache.Order, id)
+  return cursor, true
+}
+
+func (cache * CursorCache) Expired(cursor * Cursor, now time.Time) bool {
+  return cache.TTL > 0 && now.Sub(cursor.Created) > cache.TTL
+}
+
+// version:id:offset:limit, so tokens from an older index are refused
+func EncodeCursor(version string, id string, offset int, limit int) string {
+  return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s:%d:%d", version, id, offset, limit)))
+}
+
+func DecodeCursor(token string) (version string, id string, offset int, limit int, err error) {
+  err = fmt.Errorf("cursor '%s' is not valid", token)
+  data, decodeErr := base64.RawURLEncoding.DecodeString(token)
+  if decodeErr != nil {
+    return
+  }
+  parts := strings.Split(string(data), ":")
+  if len(parts) != 4 {
+    return
+  }
+  var offsetErr, limitErr error
+  offset, offsetErr = strconv.Atoi(parts[2])
+  limit, limitErr = strconv.Atoi(parts[3])
+  if offsetErr != nil || limitErr != nil || offset < 0 || limit < 0 {
+    return
+  }
+  return parts[0], parts[1], offset, limit, nil
+}
+
+// offset and limit parameters; limit is capped at the server maximum
+func (search * Search) ParsePage(queries url.Values) (offset int, limit int, errors []string) {
+  limit = RESULTS_TO_RETURN
+  if search.MaxLimit > 0 && limit > search.MaxLimit {
+    limit = search.MaxLimit
+  }
+  if value, has := queries["offset"]; has {
+    parsed, err := strconv.Atoi(value[0])
+    if err != nil || parsed < 0 {
+      errors = append(errors, "offset '" + value[0] + "' is not a non-negative integer")
+    } else {
+      offset = parsed
+    }
+  }
+  if value, has := queries["limit"]; has {
+    parsed, err := strconv.Atoi(value[0])
+    if err != nil || parsed < 0 {
+      errors = append(errors, "limit '" + value[0] + "' is not a non-negative integer")
+    } else {
+      limit = parsed
+    }
+  }
+  if search.MaxLimit > 0 && limit > search.MaxLimit {
+    limit = search.MaxLimit
+  }
+  return
+}
+
+// results from offset to offset + limit, or none past the end
+func PageResults(results SearchResults, offset int, limit int) SearchResults {
+  if offset > len(results) {
+    offset = len(results)
+  }
+  results = results[offset:]
+  if len(results) > limit {
+    results = results[:limit]
+  }
+  return results
+}
+
+// cursor=token continues a search without running it again
+func (search * Search) Resume(queries url.Values, schema * Schema) map[string]interface{} {
+  token := queries.Get("cursor")
+  version, id, offset, limit, err := DecodeCursor(token)
+  // tokens are not signed, so the limit in them is capped again
+  if search.MaxLimit > 0 && limit > search.MaxLimit {
+    limit = search.MaxLimit
+  }
+  if err == nil && version != search.Version {
+    err = fmt.Errorf("cursor '%s' is from an older index", token)
+  }
+  var cursor * Cursor
+  if err == nil {
+    var has bool
+    if cursor, has = search.Cursors.Get(id); !has {
+      err = fmt.Errorf("cursor '%s' has expired", token)
+    }
+  }
+  if err == nil && !cursor.Covers(offset, limit) {
+    err = fmt.Errorf("cursor '%s' is past the results kept for cursors; use offset", token)
+  }
+  if err != nil {
+    return map[string]interface{}{
+      "total": 0,
+      "offset": 0,
+      "limit": 0,
+      "errors": []string{err.Error()},
+      "results": []map[string]interface{}{},
+    }
+  }
+  return search.Page(cursor, id, cursor.Results, offset, limit, make([]string, 0), schema)
+}
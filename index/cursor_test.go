@@ -0,0 +1,186 @@
+package index
+
+import (
+  "encoding/base64"
+  "net/url"
+  "reflect"
+  "strings"
+  "testing"
+  "time"
+)
+
+func TestDecodeCursor(t *testing.T) {
+  encode := func(str string) string {
+    return base64.RawURLEncoding.EncodeToString([]byte(str))
+  }
+  tests := []struct {
+    token string
+    version string
+    id string
+    offset int
+    limit int
+    valid bool
+  }{
+    {EncodeCursor("v1", "a", 20, 10), "v1", "a", 20, 10, true},
+    {EncodeCursor("v1", "a", 0, 0), "v1", "a", 0, 0, true},
+    {encode("v1:a:5:1000"), "v1", "a", 5, 1000, true},
+    {"", "", "", 0, 0, false},
+    {"not base64!", "", "", 0, 0, false},
+    {encode("v1:a:20"), "", "", 0, 0, false},
+    {encode("v1:a:20:10:5"), "", "", 0, 0, false},
+    {encode("v1:a:x:10"), "", "", 0, 0, false},
+    {encode("v1:a:20:"), "", "", 0, 0, false},
+    {encode("v1:a:-1:10"), "", "", 0, 0, false},
+    {encode("v1:a:0:-10"), "", "", 0, 0, false},
+    {EncodeCursor("v1", "a", 20, 10) + "=", "", "", 0, 0, false},
+  }
+  for _, test := range tests {
+    version, id, offset, limit, err := DecodeCursor(test.token)
+    if (err == nil) != test.valid {
+      t.Errorf("DecodeCursor(%q) error = %v, want valid %v", test.token, err, test.valid)
+    } else if test.valid && (version != test.version || id != test.id || offset != test.offset || limit != test.limit) {
+      t.Errorf("DecodeCursor(%q) = %s, %s, %d, %d", test.token, version, id, offset, limit)
+    }
+  }
+}
+
+func TestParsePage(t *testing.T) {
+  search := &Search{MaxLimit: 50}
+  tests := []struct {
+    queries url.Values
+    offset int
+    limit int
+    errors []string
+  }{
+    {url.Values{}, 0, RESULTS_TO_RETURN, nil},
+    {url.Values{"offset": {"40"}, "limit": {"5"}}, 40, 5, nil},
+    {url.Values{"limit": {"0"}}, 0, 0, nil},
+    {url.Values{"limit": {"1000"}}, 0, 50, nil},
+    {url.Values{"offset": {"-1"}}, 0, RESULTS_TO_RETURN, []string{"offset '-1' is not a non-negative integer"}},
+    {url.Values{"offset": {"x"}, "limit": {"-5"}}, 0, RESULTS_TO_RETURN, []string{"offset 'x' is not a non-negative integer", "limit '-5' is not a non-negative integer"}},
+  }
+  for _, test := range tests {
+    offset, limit, errors := search.ParsePage(test.queries)
+    if offset != test.offset || limit != test.limit || !reflect.DeepEqual(errors, test.errors) {
+      t.Errorf("ParsePage(%v) = %d, %d, %q, want %d, %d, %q", test.queries, offset, limit, errors, test.offset, test.limit, test.errors)
+    }
+  }
+}
+
+func TestPageResults(t *testing.T) {
+  results := SearchResults{{Item: 0}, {Item: 1}, {Item: 2}, {Item: 3}, {Item: 4}}
+  tests := []struct {
+    results SearchResults
+    offset int
+    limit int
+    items []int
+  }{
+    {results, 0, 2, []int{0, 1}},
+    {results, 3, 2, []int{3, 4}},
+    {results, 4, 2, []int{4}},
+    {results, 5, 2, []int{}},
+    {results, 9, 2, []int{}},
+    {results, 1, 0, []int{}},
+    {SearchResults{}, 0, 10, []int{}},
+  }
+  for _, test := range tests {
+    page := PageResults(test.results, test.offset, test.limit)
+    items := make([]int, len(page))
+    for i, x := range page {
+      items[i] = x.Item
+    }
+    if !reflect.DeepEqual(items, test.items) {
+      t.Errorf("PageResults(%d, %d) = %v, want %v", test.offset, test.limit, items, test.items)
+    }
+  }
+}
+
+func TestCursorCovers(t *testing.T) {
+  results := make(SearchResults, 100)
+  for i := range results {
+    results[i].Item = i
+  }
+  tests := []struct {
+    window int
+    offset int
+    limit int
+    covers bool
+  }{
+    {45, 0, 10, true},
+    {45, 40, 5, true},
+    {45, 40, 10, false},
+    {45, 90, 10, false},
+    {100, 90, 20, true},
+    {1000, 500, 10, true},
+    {0, 0, 10, false},
+  }
+  for _, test := range tests {
+    cursor := NewCursor(&SearchRequest{}, results, nil, nil, test.window)
+    if covers := cursor.Covers(test.offset, test.limit); covers != test.covers || cursor.Total != 100 {
+      t.Errorf("window %d: Covers(%d, %d) = %v, want %v", test.window, test.offset, test.limit, covers, test.covers)
+    }
+  }
+}
+
+func TestCursorCache(t *testing.T) {
+  cache := CursorCache{Size: 2, TTL: time.Minute}
+  first := cache.Put(&Cursor{})
+  second := cache.Put(&Cursor{})
+  if _, has := cache.Get(first); !has {
+    t.Fatal("first cursor is missing")
+  }
+  // second is now the least recently used
+  third := cache.Put(&Cursor{})
+  if _, has := cache.Get(second); has {
+    t.Error("second cursor was not evicted")
+  }
+  if _, has := cache.Get(first); !has {
+    t.Error("first cursor was evicted")
+  }
+  cache.Cursors[third].Created = time.Now().Add(-2 * time.Minute)
+  if _, has := cache.Get(third); has {
+    t.Error("expired cursor was returned")
+  }
+  if len(cache.Cursors) != 1 || len(cache.Order) != 1 {
+    t.Errorf("cache keeps %d cursors in order %v, want 1", len(cache.Cursors), cache.Order)
+  }
+}
+
+func TestResume(t *testing.T) {
+  collection := FixtureCollection()
+  search := collection.search
+  first := search.Search(url.Values{"color": {"red"}, "sort": {"popularity"}, "limit": {"10"}}, collection.schema)
+  next, has := first["next"].(string)
+  if !has {
+    t.Fatal("first page has no next cursor")
+  }
+  _, id, _, _, _ := DecodeCursor(next)
+  tests := []struct {
+    token string
+    offset int
+    limit int
+    first float64 // popularity of the first result
+    err string
+  }{
+    {next, 10, 10, 30, ""},
+    {EncodeCursor(search.Version, id, 130, 10), 130, 10, 390, ""},
+    {EncodeCursor(search.Version, id, 0, 1000), 0, search.MaxLimit, 0, ""},
+    {"nope", 0, 0, 0, "cursor 'nope' is not valid"},
+    {EncodeCursor("old", id, 10, 10), 0, 0, 0, "is from an older index"},
+    {EncodeCursor(search.Version, "unknown", 10, 10), 0, 0, 0, "has expired"},
+  }
+  for _, test := range tests {
+    output := search.Search(url.Values{"cursor": {test.token}, "color": {"blue"}}, collection.schema)
+    if test.err != "" {
+      errors, _ := output["errors"].([]string)
+      if len(errors) != 1 || !strings.Contains(errors[0], test.err) {
+        t.Errorf("cursor %q errors = %v, want %q", test.token, output["errors"], test.err)
+      }
+      continue
+    }
+    results := output["results"].([]map[string]interface{})
+    if output["offset"] != test.offset || output["limit"] != test.limit || output["total"] != 134 || len(results) == 0 || results[0]["popularity"] != test.first {
+      t.Errorf("cursor %q = offset %v, limit %v, total %v, want %d, %d, 134 starting at %v", test.token, output["offset"], output["limit"], output["total"], test.offset, test.limit, test.first)
+    }
+  }
+}
@@ -0,0 +1,314 @@
+package index
+
+import (
+  "bytes"
+  "encoding/json"
+  "fmt"
+  "net/url"
+  "regexp"
+  "sort"
+  "strconv"
+  "strings"
+)
+
+// body of POST search requests
+type SearchBody struct {
+  Query interface{} `json:"query"`
+  Sort interface{} `json:"sort"`
+  From * int `json:"from"`
+  Size * int `json:"size"`
+  Fields []string `json:"fields"`
+  Aggs []map[string]DSLAggregation `json:"aggs"`
+  Knn * KnnQuery `json:"knn"`
+}
+
+// {"stats": {"field": "price"}}, {"percentiles": {"field": "price", "percents": [50, 99]}}
+// or {"histogram": {"field": "price", "interval": 10}}
+type DSLAggregation struct {
+  Field string `json:"field"`
+  Percents []float64 `json:"percents"`
+  Interval * float64 `json:"interval"`
+}
+
+// query parameters still filter; the body adds to them
+func (search * Search) SearchPost(queries url.Values, body []byte, schema * Schema) map[string]interface{} {
+  if queries.Get("cursor") != "" {
+    return search.Resume(queries, schema)
+  }
+  var searchBody SearchBody
+  decoder := json.NewDecoder(bytes.NewReader(body))
+  decoder.DisallowUnknownFields()
+  err := decoder.Decode(&searchBody)
+  if err != nil && len(bytes.TrimSpace(body)) == 0 {
+    err = nil
+  }
+
+  // options become query parameters so both forms share one code path
+  values := make(url.Values, len(queries))
+  for key, value := range queries {
+    values[key] = value
+  }
+  errors := make([]string, 0)
+  if searchBody.Sort != nil {
+    if sortValue, sortErr := DSLSort(searchBody.Sort); sortErr == nil {
+      values.Set("sort", sortValue)
+    } else {
+      errors = append(errors, sortErr.Error())
+    }
+  }
+  if searchBody.From != nil {
+    values.Set("offset", strconv.Itoa(*searchBody.From))
+  }
+  if searchBody.Size != nil {
+    values.Set("limit", strconv.Itoa(*searchBody.Size))
+  }
+  if searchBody.Fields != nil {
+    values.Set("fields", strings.Join(searchBody.Fields, ","))
+  }
+
+  request := search.NewRequest(values)
+  request.Errors = append(request.Errors, errors...)
+  if err != nil {
+    request.Errors = append(request.Errors, "invalid body: " + err.Error())
+    return search.Output(request, schema, SearchResults{})
+  }
+  if request.Aggs, err = DSLAggregations(searchBody.Aggs); err != nil {
+    request.Errors = append(request.Errors, err.Error())
+  }
+  if searchBody.Knn != nil {
+    request.Knn = searchBody.Knn
+  }
+  if searchBody.Query != nil {
+    if request.Query, err = search.ParseDSL(searchBody.Query, "query"); err != nil {
+      request.Errors = append(request.Errors, err.Error())
+      return search.Output(request, schema, SearchResults{})
+    }
+    request.Explain = append(request.Explain, map[string]interface{}{"query": request.Query})
+  }
+  return search.Run(request, schema)
+}
+
+// {"bool": {...}}, {"match_all": {}}, {"field": value} or {"field": {"filter": value}}
+func (search * Search) ParseDSL(query interface{}, path string) (* QueryNode, error) {
+  object, isObject := query.(map[string]interface{})
+  if !isObject || len(object) != 1 {
+    return nil, fmt.Errorf("%s: expected an object with one key", path)
+  }
+  for key, value := range object {
+    path += "." + key
+    switch key {
+      case "bool":
+        return BoolQuery(value, path, search.ParseDSL)
+      case "match_all":
+        return &QueryNode{Operator: "and"}, nil
+    }
+    return search.ParseDSLFilter(key, value, path)
+  }
+  return nil, nil
+}
+
+// must and filter are required, must_not excluded; should is required only
+// without must or filter, otherwise it adds to the score
+func BoolQuery(value interface{}, path string, parse func(interface{}, string) (* QueryNode, error)) (* QueryNode, error) {
+  object, isObject := value.(map[string]interface{})
+  if !isObject {
+    return nil, fmt.Errorf("%s: expected an object", path)
+  }
+  clauses := make(map[string][]*QueryNode)
+  for key, value := range object {
+    switch key {
+      case "must", "should", "must_not", "filter":
+        list, isList := value.([]interface{})
+        if !isList {
+          list = []interface{}{value}
+        }
+        for i, item := range list {
+          node, err := parse(item, fmt.Sprintf("%s.%s[%d]", path, key, i))
+          if err != nil {
+            return nil, err
+          }
+          clauses[key] = append(clauses[key], node)
+        }
+        break;
+      default:
+        return nil, fmt.Errorf("%s: unknown clause '%s'", path, key)
+    }
+  }
+  node := &QueryNode{Operator: "and"}
+  node.Children = append(clauses["must"], clauses["filter"]...)
+  for _, child := range clauses["must_not"] {
+    node.Children = append(node.Children, &QueryNode{Operator: "not", Children: []*QueryNode{child}})
+  }
+  if len(clauses["should"]) > 0 {
+    if len(clauses["must"]) + len(clauses["filter"]) > 0 {
+      for _, child := range clauses["should"] {
+        node.Children = append(node.Children, &QueryNode{Operator: "optional", Children: []*QueryNode{child}})
+      }
+    } else {
+      node.Children = append(node.Children, &QueryNode{Operator: "or", Children: clauses["should"]})
+    }
+  }
+  return node, nil
+}
+
+func (search * Search) ParseDSLFilter(field string, value interface{}, path string) (* QueryNode, error) {
+  searchField, has := search.Fields[field]
+  if !has || searchField.Filters == nil {
+    return nil, fmt.Errorf("%s: unknown field '%s'", path, field)
+  }
+  filter := searchField.Filters[0]
+  if object, isObject := value.(map[string]interface{}); isObject {
+    if len(object) != 1 {
+      return nil, fmt.Errorf("%s: expected one filter", path)
+    }
+    for key, filterValue := range object {
+      filter = key
+      value = filterValue
+    }
+    path += "." + filter
+  }
+  supported := false
+  for _, name := range searchField.Filters {
+    supported = supported || name == filter
+  }
+  if !supported {
+    return nil, fmt.Errorf("%s: field '%s' does not support filter '%s'", path, field, filter)
+  }
+  text, err := DSLValue(filter, value, path)
+  if err != nil {
+    return nil, err
+  }
+  return &QueryNode{Field: field, Filter: filter, Value: text}, nil
+}
+
+// filter value as written in query parameters. Lists are comma separated
+// for in and notIn, and literal alternatives for within and notWithin; other
+// filters take one value.
+func DSLValue(filter string, value interface{}, path string) (string, error) {
+  switch value := value.(type) {
+    case string:
+      return value, nil
+    case float64:
+      return strconv.FormatFloat(value, 'f', -1, 64), nil
+    case bool:
+      return strconv.FormatBool(value), nil
+    case []interface{}:
+      separator := ","
+      switch strings.TrimSuffix(filter, "IgnoreCase") {
+        case "in", "notIn":
+          break;
+        case "within", "notWithin":
+          separator = "|"
+          break;
+        default:
+          return "", fmt.Errorf("%s: filter '%s' takes one value, not a list", path, filter)
+      }
+      values := make([]string, len(value))
+      for i, item := range value {
+        itemPath := fmt.Sprintf("%s[%d]", path, i)
+        if _, isList := item.([]interface{}); isList {
+          return "", fmt.Errorf("%s: unsupported value", itemPath)
+        }
+        text, err := DSLValue(filter, item, itemPath)
+        if err != nil {
+          return "", err
+        }
+        if separator == "|" {
+          text = regexp.QuoteMeta(text)
+        } else if strings.Contains(text, ",") {
+          return "", fmt.Errorf("%s: '%s' contains ','", itemPath, text)
+        }
+        values[i] = text
+      }
+      return strings.Join(values, separator), nil
+  }
+  return "", fmt.Errorf("%s: unsupported value", path)
+}
+
+// percents are only for percentiles and interval only for histogram
+func DSLAggregations(list []map[string]DSLAggregation) ([]SearchAggregation, error) {
+  aggregations := make([]SearchAggregation, 0, len(list))
+  for i, item := range list {
+    if len(item) != 1 {
+      return nil, fmt.Errorf("aggs[%d]: expected an object with one key", i)
+    }
+    for function, options := range item {
+      path := fmt.Sprintf("aggs[%d].%s", i, function)
+      if options.Field == "" {
+        return nil, fmt.Errorf("%s: expected a field", path)
+      }
+      aggregation := SearchAggregation{Function: function, Field: options.Field}
+      if options.Percents != nil {
+        if function != "percentiles" {
+          return nil, fmt.Errorf("%s: percents are only for percentiles", path)
+        }
+        aggregation.Arguments = options.Percents
+      }
+      if options.Interval != nil {
+        if function != "histogram" {
+          return nil, fmt.Errorf("%s: interval is only for histogram", path)
+        }
+        aggregation.Arguments = []float64{*options.Interval}
+      }
+      aggregations = append(aggregations, aggregation)
+    }
+  }
+  return aggregations, nil
+}
+
+// "price", "-price", {"price": "desc"}, {"price": {"order": "desc", "missing": "_first"}}
+// or a list of them
+func DSLSort(value interface{}) (string, error) {
+  switch value := value.(type) {
+    case string:
+      return value, nil
+    case []interface{}:
+      keys := make([]string, len(value))
+      for i, item := range value {
+        key, err := DSLSort(item)
+        if err != nil {
+          return "", err
+        }
+        keys[i] = key
+      }
+      return strings.Join(keys, ","), nil
+    case map[string]interface{}:
+      fields := make([]string, 0, len(value))
+      for field, _ := range value {
+        fields = append(fields, field)
+      }
+      sort.Strings(fields)
+      keys := make([]string, len(fields))
+      for i, field := range fields {
+        order := value[field]
+        missing := interface{}("_last")
+        if object, isObject := order.(map[string]interface{}); isObject {
+          order = object["order"]
+          if option, has := object["missing"]; has {
+            missing = option
+          }
+        }
+        switch order {
+          case "asc":
+            keys[i] = field
+            break;
+          case "desc":
+            keys[i] = "-" + field
+            break;
+          default:
+            return "", fmt.Errorf("sort: order of '%s' must be asc or desc", field)
+        }
+        switch missing {
+          case "_first":
+            keys[i] += ":nullsFirst"
+            break;
+          case "_last":
+            break;
+          default:
+            return "", fmt.Errorf("sort: missing of '%s' must be _first or _last", field)
+        }
+      }
+      return strings.Join(keys, ","), nil
+  }
+  return "", fmt.Errorf("sort: expected a field name, an object or a list")
+}
@@ -0,0 +1,169 @@
+package index
+
+import (
+  "encoding/json"
+  "reflect"
+  "strings"
+  "testing"
+)
+
+func TestParseDSL(t *testing.T) {
+  search := FixtureCollection().search
+  leaf := func(field string, filter string, value string) * QueryNode {
+    return &QueryNode{Field: field, Filter: filter, Value: value}
+  }
+  node := func(operator string, children ...* QueryNode) * QueryNode {
+    return &QueryNode{Operator: operator, Children: children}
+  }
+  tests := []struct {
+    query string
+    want * QueryNode
+    err string
+  }{
+    {`{"color": "red"}`, leaf("color", "within", "red"), ""},
+    {`{"price": {"lessThan": 10}}`, leaf("price", "lessThan", "10"), ""},
+    {`{"flag": true}`, leaf("flag", "equals", "true"), ""},
+    // list items of within match literally
+    {`{"color": ["red", "blue"]}`, leaf("color", "within", "red|blue"), ""},
+    {`{"color": {"notWithin": ["red", "a.b"]}}`, leaf("color", "notWithin", "red|a\\.b"), ""},
+    {`{"brand": {"in": ["acme", "globex"]}}`, leaf("brand", "in", "acme,globex"), ""},
+    {`{"price": {"in": [1, 2.5]}}`, leaf("price", "in", "1,2.5"), ""},
+    {`{"match_all": {}}`, node("and"), ""},
+    {`{"bool": {"must": [{"color": "red"}], "must_not": {"flag": true}}}`, node("and", leaf("color", "within", "red"), node("not", leaf("flag", "equals", "true"))), ""},
+    {`{"bool": {"should": [{"color": "red"}, {"color": "blue"}]}}`, node("and", node("or", leaf("color", "within", "red"), leaf("color", "within", "blue"))), ""},
+    {`{"bool": {"filter": {"color": "red"}, "should": {"brand": "acme"}}}`, node("and", leaf("color", "within", "red"), node("optional", leaf("brand", "within", "acme"))), ""},
+    {`{"nope": 1}`, nil, "query.nope: unknown field 'nope'"},
+    {`{"price": {"regex": "x"}}`, nil, "query.price.regex: field 'price' does not support filter 'regex'"},
+    {`{"price": {"lessThan": null}}`, nil, "query.price.lessThan: unsupported value"},
+    {`{"price": {"lessThan": [1, 2]}}`, nil, "query.price.lessThan: filter 'lessThan' takes one value, not a list"},
+    {`{"brand": {"prefix": ["a", "g"]}}`, nil, "query.brand.prefix: filter 'prefix' takes one value, not a list"},
+    {`{"brand": {"in": ["acme", "a,b"]}}`, nil, "query.brand.in[1]: 'a,b' contains ','"},
+    {`{"brand": {"in": [["acme"]]}}`, nil, "query.brand.in[0]: unsupported value"},
+    {`{"bool": {"maybe": []}}`, nil, "query.bool: unknown clause 'maybe'"},
+    {`{"bool": {"must": [{"color": "red"}, {"nope": 1}]}}`, nil, "query.bool.must[1].nope: unknown field 'nope'"},
+    {`{"color": "red", "brand": "acme"}`, nil, "query: expected an object with one key"},
+    {`[]`, nil, "query: expected an object with one key"},
+  }
+  for _, test := range tests {
+    var query interface{}
+    if err := json.Unmarshal([]byte(test.query), &query); err != nil {
+      t.Fatal(err)
+    }
+    got, err := search.ParseDSL(query, "query")
+    if test.err != "" {
+      if err == nil || err.Error() != test.err {
+        t.Errorf("ParseDSL(%s) error = %v, want %q", test.query, err, test.err)
+      }
+    } else if err != nil || !reflect.DeepEqual(got, test.want) {
+      encoded, _ := json.Marshal(got)
+      t.Errorf("ParseDSL(%s) = %s, %v", test.query, encoded, err)
+    }
+  }
+}
+
+func TestDSLSort(t *testing.T) {
+  tests := []struct {
+    sort string
+    want string
+    err bool
+  }{
+    {`"price"`, "price", false},
+    {`"-price"`, "-price", false},
+    {`{"price": "desc"}`, "-price", false},
+    {`{"price": {"order": "asc", "missing": "_first"}}`, "price:nullsFirst", false},
+    {`["brand", {"price": "desc"}]`, "brand,-price", false},
+    {`{"price": "up"}`, "", true},
+    {`{"price": {"order": "asc", "missing": "_middle"}}`, "", true},
+    {`10`, "", true},
+  }
+  for _, test := range tests {
+    var value interface{}
+    if err := json.Unmarshal([]byte(test.sort), &value); err != nil {
+      t.Fatal(err)
+    }
+    got, err := DSLSort(value)
+    if (err != nil) != test.err || got != test.want {
+      t.Errorf("DSLSort(%s) = %q, %v, want %q", test.sort, got, err, test.want)
+    }
+  }
+}
+
+func TestDSLAggregations(t *testing.T) {
+  tests := []struct {
+    aggs string
+    want []SearchAggregation
+    err string
+  }{
+    {`[{"stats": {"field": "price"}}, {"avg": {"field": "popularity"}}]`, []SearchAggregation{{Function: "stats", Field: "price"}, {Function: "avg", Field: "popularity"}}, ""},
+    {`[{"percentiles": {"field": "price", "percents": [50, 99]}}]`, []SearchAggregation{{Function: "percentiles", Field: "price", Arguments: []float64{50, 99}}}, ""},
+    {`[{"histogram": {"field": "price", "interval": 10}}]`, []SearchAggregation{{Function: "histogram", Field: "price", Arguments: []float64{10}}}, ""},
+    {`[]`, []SearchAggregation{}, ""},
+    {`[{"stats": {"field": "price"}, "avg": {"field": "price"}}]`, nil, "aggs[0]: expected an object with one key"},
+    {`[{"stats": {}}]`, nil, "aggs[0].stats: expected a field"},
+    {`[{"stats": {"field": "price", "percents": [50]}}]`, nil, "aggs[0].stats: percents are only for percentiles"},
+    {`[{"percentiles": {"field": "price", "interval": 5}}]`, nil, "aggs[0].percentiles: interval is only for histogram"},
+  }
+  for _, test := range tests {
+    var list []map[string]DSLAggregation
+    if err := json.Unmarshal([]byte(test.aggs), &list); err != nil {
+      t.Fatal(err)
+    }
+    got, err := DSLAggregations(list)
+    if test.err != "" {
+      if err == nil || err.Error() != test.err {
+        t.Errorf("DSLAggregations(%s) error = %v, want %q", test.aggs, err, test.err)
+      }
+    } else if err != nil || !reflect.DeepEqual(got, test.want) {
+      t.Errorf("DSLAggregations(%s) = %+v, %v, want %+v", test.aggs, got, err, test.want)
+    }
+  }
+}
+
+// sort, from, size, fields and aggs of the body work as their query parameters
+func TestSearchPostOptions(t *testing.T) {
+  collection := FixtureCollection()
+  body := `{
+    "query": {"color": {"within": ["red", "blue"]}},
+    "sort": [{"popularity": "desc"}], "from": 2, "size": 3, "fields": ["popularity"],
+    "aggs": [{"max": {"field": "popularity"}}, {"percentiles": {"field": "popularity", "percents": [0]}}]
+  }`
+  output := collection.search.SearchPost(map[string][]string{"aggs": {"min:popularity"}}, []byte(body), collection.schema)
+  if errors, has := output["errors"]; has {
+    t.Fatal(errors)
+  }
+  if output["total"] != 267 || output["offset"] != 2 || output["limit"] != 3 {
+    t.Errorf("total, offset, limit = %v, %v, %v, want 267, 2, 3", output["total"], output["offset"], output["limit"])
+  }
+  results := output["results"].([]map[string]interface{})
+  for i, want := range []float64{396, 394, 393} {
+    if i >= len(results) || results[i]["popularity"] != want || results[i]["title"] != nil {
+      t.Fatalf("results = %v, want popularity %v only", results, want)
+    }
+  }
+  want := map[string]interface{}{
+    "min(popularity)": 0.0,
+    "max(popularity)": 399.0,
+    "percentiles(popularity)": map[string]interface{}{"0": 0.0},
+  }
+  if !reflect.DeepEqual(output["aggregations"], want) {
+    t.Errorf("aggregations = %v, want %v", output["aggregations"], want)
+  }
+
+  tests := []struct {
+    body string
+    err string
+  }{
+    // the wording of json errors differs between go versions
+    {`{"aggs": ["stats:price"]}`, "invalid body: json: cannot unmarshal string into "},
+    {`{"aggs": [{"stats": {"field": "price", "size": 5}}]}`, `invalid body: json: unknown field "size"`},
+    {`{"aggs": [{"median": {"field": "price"}}]}`, "aggregate 'median' is not supported"},
+    {`{"aggs": [{"avg": {"field": "popularity"}}, {"avg": {"field": "popularity"}}]}`, "aggregation 'avg(popularity)' is given twice"},
+  }
+  for _, test := range tests {
+    output := collection.search.SearchPost(nil, []byte(test.body), collection.schema)
+    errors, _ := output["errors"].([]string)
+    if len(errors) != 1 || !strings.HasPrefix(errors[0], test.err) {
+      t.Errorf("%s: errors = %q, want %q", test.body, errors, test.err)
+    }
+  }
+}
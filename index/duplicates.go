@@ -0,0 +1,283 @@
+package index
+
+import (
+  "math"
+  "math/rand"
+  "net/url"
+  "sort"
+  "strconv"
+  "sync"
+)
+
+const MINHASH_PERMUTATIONS int = 128
+
+// thresholds other than the configured one are rounded to steps of 1 /
+// DUPLICATE_THRESHOLD_STEPS, so at most that many clusterings are kept
+const DUPLICATE_THRESHOLD_STEPS float64 = 20
+
+// minhash signatures of the full text terms of every record; built on first use
+type DuplicateIndex struct {
+  Terms [][]uint64
+  Signatures [][]uint64
+  Clusters map[float64][][]int // by threshold
+  Lock sync.Mutex
+}
+
+// rows per band so that the lsh s-curve crosses at threshold
+func MinHashRows(threshold float64) int {
+  best := 1
+  bestError := math.Inf(1)
+  for rows := 1; rows <= MINHASH_PERMUTATIONS; rows++ {
+    if MINHASH_PERMUTATIONS % rows != 0 {
+      continue
+    }
+    bands := float64(MINHASH_PERMUTATIONS / rows)
+    if err := math.Abs(math.Pow(1 / bands, 1 / float64(rows)) - threshold); err < bestError {
+      best = rows
+      bestError = err
+    }
+  }
+  return best
+}
+
+// sorted distinct field and term code pairs
+func (search * Search) RecordTerms(fields []string, item int) []uint64 {
+  terms := make([]uint64, 0, 16)
+  seen := make(map[uint64]bool)
+  for i, field := range fields {
+    for _, code := range search.TextIndexes[field].Records[item] {
+      term := uint64(i) << 32 | uint64(code)
+      if code != TEXT_GAP && !seen[term] {
+        seen[term] = true
+        terms = append(terms, term)
+      }
+    }
+  }
+  sort.Slice(terms, func(i, j int) bool { return terms[i] < terms[j] })
+  return terms
+}
+
+func Jaccard(a, b []uint64) float64 {
+  if len(a) == 0 && len(b) == 0 {
+    return 0
+  }
+  common := 0
+  for i, j := 0, 0; i < len(a) && j < len(b); {
+    if a[i] == b[j] {
+      common++
+      i++
+      j++
+    } else if a[i] < b[j] {
+      i++
+    } else {
+      j++
+    }
+  }
+  return float64(common) / float64(len(a) + len(b) - common)
+}
+
+func (search * Search) DuplicateIndex(schema * Schema) * DuplicateIndex {
+  duplicates := &search.Duplicates
+  duplicates.Lock.Lock()
+  defer duplicates.Lock.Unlock()
+  if duplicates.Signatures != nil {
+    return duplicates
+  }
+
+  random := rand.New(rand.NewSource(1))
+  seeds := make([]uint64, MINHASH_PERMUTATIONS)
+  for k := range seeds {
+    seeds[k] = random.Uint64()
+  }
+
+  fields := search.TextFields("search")
+  duplicates.Terms = make([][]uint64, schema.TotalItems)
+  duplicates.Signatures = make([][]uint64, schema.TotalItems)
+  for item := 0; item < schema.TotalItems; item++ {
+    terms := search.RecordTerms(fields, item)
+    if len(terms) == 0 {
+      continue
+    }
+    signature := make([]uint64, MINHASH_PERMUTATIONS)
+    for k := range signature {
+      signature[k] = math.MaxUint64
+      for _, term := range terms {
+        if hash := MixHash(term ^ seeds[k]); hash < signature[k] {
+          signature[k] = hash
+        }
+      }
+    }
+    duplicates.Terms[item] = terms
+    duplicates.Signatures[item] = signature
+  }
+  duplicates.Clusters = make(map[float64][][]int)
+  return duplicates
+}
+
+// splitmix64 finaliser; one seed per permutation
+func MixHash(x uint64) uint64 {
+  x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+  x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+  return x ^ (x >> 31)
+}
+
+// groups of records linked by pairs whose terms overlap by at least
+// threshold (jaccard), so two members of a group may overlap less; largest
+// first
+func (duplicates * DuplicateIndex) Find(threshold float64) [][]int {
+  duplicates.Lock.Lock()
+  defer duplicates.Lock.Unlock()
+  if clusters, has := duplicates.Clusters[threshold]; has {
+    return clusters
+  }
+
+  parents := make([]int, len(duplicates.Signatures))
+  for i := range parents {
+    parents[i] = i
+  }
+  var find func(int) int
+  find = func(i int) int {
+    if parents[i] != i {
+      parents[i] = find(parents[i])
+    }
+    return parents[i]
+  }
+
+  rows := MinHashRows(threshold)
+  for band := 0; band < MINHASH_PERMUTATIONS / rows; band++ {
+    buckets := make(map[uint64][]int)
+    for item, signature := range duplicates.Signatures {
+      if signature == nil {
+        continue
+      }
+      key := uint64(14695981039346656037)
+      for _, value := range signature[band * rows:(band + 1) * rows] {
+        key = (key ^ value) * 1099511628211
+      }
+      buckets[key] = append(buckets[key], item)
+    }
+    // every pair of a bucket is a candidate; pairs already in one cluster
+    // are not compared again
+    for _, bucket := range buckets {
+      for i, item := range bucket {
+        for _, other := range bucket[i + 1:] {
+          if find(item) != find(other) && Jaccard(duplicates.Terms[item], duplicates.Terms[other]) >= threshold {
+            parents[find(other)] = find(item)
+          }
+        }
+      }
+    }
+  }
+
+  groups := make(map[int][]int)
+  for item, signature := range duplicates.Signatures {
+    if signature != nil {
+      root := find(item)
+      groups[root] = append(groups[root], item)
+    }
+  }
+  clusters := make([][]int, 0)
+  for _, group := range groups {
+    if len(group) > 1 {
+      clusters = append(clusters, group)
+    }
+  }
+  sort.Slice(clusters, func(i, j int) bool {
+    if len(clusters[i]) == len(clusters[j]) {
+      return clusters[i][0] < clusters[j][0]
+    }
+    return len(clusters[i]) > len(clusters[j])
+  })
+  duplicates.Clusters[threshold] = clusters
+  return clusters
+}
+
+// cluster of each record, -1 when it has no near duplicates
+func ClusterIndex(clusters [][]int, totalItems int) []int {
+  index := make([]int, totalItems)
+  for i := range index {
+    index[i] = -1
+  }
+  for c, cluster := range clusters {
+    for _, item := range cluster {
+      index[item] = c
+    }
+  }
+  return index
+}
+
+// keeps the first result of each cluster; returns how many were dropped per kept item
+func CollapseDuplicates(clusterIndex []int, input SearchResults) (SearchResults, map[int]int) {
+  kept := make(map[int]int)
+  collapsed := make(map[int]int)
+  output := input[:0]
+  for _, x := range input {
+    cluster := clusterIndex[x.Item]
+    if cluster == -1 {
+      output = append(output, x)
+    } else if item, has := kept[cluster]; has {
+      collapsed[item]++
+    } else {
+      kept[cluster] = x.Item
+      output = append(output, x)
+    }
+  }
+  return output, collapsed
+}
+
+// near duplicate clusters among records matching the queries
+func (search * Search) FindDuplicates(queries url.Values, schema * Schema) map[string]interface{} {
+  request := search.NewRequest(queries)
+  output := map[string]interface{}{}
+
+  threshold := search.DuplicateThreshold
+  if value, has := queries["threshold"]; has {
+    if parsed, err := strconv.ParseFloat(value[0], 64); err == nil && parsed > 0 && parsed <= 1 {
+      if parsed != threshold {
+        threshold = math.Max(1, math.Round(parsed * DUPLICATE_THRESHOLD_STEPS)) / DUPLICATE_THRESHOLD_STEPS
+      }
+    } else {
+      request.Errors = append(request.Errors, "threshold '" + value[0] + "' is not a number between 0 and 1")
+    }
+  }
+  output["threshold"] = threshold
+
+  clusters := make([][]int, 0)
+  if len(search.TextIndexes) == 0 {
+    request.Errors = append(request.Errors, "collection has no full text fields")
+  } else {
+    matches := make([]bool, schema.TotalItems)
+    for _, x := range search.Filter(request, schema, SearchStart(schema)) {
+      matches[x.Item] = true
+    }
+    for _, cluster := range search.DuplicateIndex(schema).Find(threshold) {
+      ids := make([]int, 0, len(cluster))
+      for _, item := range cluster {
+        if matches[item] {
+          ids = append(ids, item)
+        }
+      }
+      if len(ids) > 1 {
+        clusters = append(clusters, ids)
+      }
+    }
+  }
+
+  offset, limit, errors := search.ParsePage(queries)
+  request.Errors = append(request.Errors, errors...)
+  output["total"] = len(clusters)
+  output["offset"] = offset
+  output["limit"] = limit
+  if len(request.Errors) > 0 {
+    output["errors"] = request.Errors
+  }
+  if offset > len(clusters) {
+    offset = len(clusters)
+  }
+  clusters = clusters[offset:]
+  if len(clusters) > limit {
+    clusters = clusters[:limit]
+  }
+  output["clusters"] = clusters
+  return output
+}
@@ -0,0 +1,104 @@
+package index
+
+import (
+  "math"
+  "net/url"
+  "reflect"
+  "testing"
+)
+
+// thresholds other than the configured one are rounded, so few clusterings
+// are cached
+func TestDuplicateThreshold(t *testing.T) {
+  collection := FixtureCollection()
+  tests := []struct {
+    threshold string
+    want float64
+    errors bool
+  }{
+    {"", 0.8, false},
+    {"0.8", 0.8, false},
+    {"0.83", 0.85, false},
+    {"0.9", 0.9, false},
+    {"0.912345", 0.9, false},
+    {"0.01", 0.05, false},
+    {"1", 1, false},
+    {"0", 0.8, true},
+    {"1.5", 0.8, true},
+    {"x", 0.8, true},
+  }
+  for _, test := range tests {
+    queries := url.Values{}
+    if test.threshold != "" {
+      queries.Set("threshold", test.threshold)
+    }
+    output := collection.search.FindDuplicates(queries, collection.schema)
+    if output["threshold"] != test.want || (output["errors"] != nil) != test.errors {
+      t.Errorf("threshold=%s: threshold = %v, errors = %v, want %v", test.threshold, output["threshold"], output["errors"], test.want)
+    }
+  }
+  if size := len(collection.search.Duplicates.Clusters); size > 5 {
+    t.Errorf("%d clusterings are cached, want at most 5", size)
+  }
+}
+
+// runs of twenty made up words; each record shifts the previous one by a word
+func DuplicateWords(from, to int) []interface{} {
+  words := make([]interface{}, 0, to - from)
+  for i := from; i < to; i++ {
+    words = append(words, "zq" + string(rune('a' + i / 26)) + string(rune('a' + i % 26)))
+  }
+  return words
+}
+
+func TestFindDuplicates(t *testing.T) {
+  words := []interface{}{
+    DuplicateWords(0, 20),
+    DuplicateWords(1, 21), // 19/21 of 0
+    DuplicateWords(2, 22), // 19/21 of 1, 18/22 of 0
+    DuplicateWords(100, 120),
+    DuplicateWords(40, 60),
+    nil,
+    DuplicateWords(100, 120),
+    DuplicateWords(200, 220),
+    DuplicateWords(41, 61),
+    DuplicateWords(300, 310),
+  }
+  collection := Index(map[string][]interface{}{"words": words}, Options{})
+  collection.Wait()
+  duplicates := collection.search.DuplicateIndex(collection.schema)
+
+  tests := []struct {
+    threshold float64
+    want [][]int
+  }{
+    {0.85, [][]int{{0, 1, 2}, {3, 6}, {4, 8}}},
+    {0.95, [][]int{{3, 6}}},
+    {0.1, [][]int{{0, 1, 2}, {3, 6}, {4, 8}}},
+  }
+  for _, test := range tests {
+    clusters := duplicates.Find(test.threshold)
+    if !reflect.DeepEqual(clusters, test.want) {
+      t.Errorf("Find(%v) = %v, want %v", test.threshold, clusters, test.want)
+    }
+    // each member has a near duplicate in its cluster
+    for _, cluster := range clusters {
+      for _, item := range cluster {
+        best := 0.0
+        for _, other := range cluster {
+          if other != item {
+            best = math.Max(best, Jaccard(duplicates.Terms[item], duplicates.Terms[other]))
+          }
+        }
+        if best < test.threshold {
+          t.Errorf("Find(%v): %d overlaps %v at most with %v", test.threshold, item, best, cluster)
+        }
+      }
+    }
+  }
+
+  output := collection.search.FindDuplicates(url.Values{"threshold": {"0.85"}, "offset": {"1"}}, collection.schema)
+  if clusters := output["clusters"]; output["total"] != 3 || !reflect.DeepEqual(clusters, [][]int{{3, 6}, {4, 8}}) {
+    t.Errorf("clusters = %v, total %v", clusters, output["total"])
+  }
+}
@@ -0,0 +1,593 @@
+package index
+
+import (
+  "bytes"
+  "encoding/json"
+  "fmt"
+  "path"
+  "sort"
+  "strconv"
+  "strings"
+  "time"
+)
+
+// subset of the elasticsearch _search api for existing clients
+
+const ELASTIC_DEFAULT_SIZE int = 10
+const ELASTIC_MAX_RESULT_WINDOW int = 10000
+
+type ElasticBody struct {
+  Query interface{} `json:"query"`
+  From * int `json:"from"`
+  Size * int `json:"size"`
+  Sort interface{} `json:"sort"`
+  Source interface{} `json:"_source"`
+  Aggs map[string]interface{} `json:"aggs"`
+  Aggregations map[string]interface{} `json:"aggregations"`
+  TrackTotalHits interface{} `json:"track_total_hits"` // totals are always exact
+}
+
+func ElasticError(errorType string, reason string) map[string]interface{} {
+  return map[string]interface{}{
+    "error": map[string]interface{}{
+      "root_cause": []map[string]interface{}{{"type": errorType, "reason": reason}},
+      "type": errorType,
+      "reason": reason,
+    },
+    "status": 400,
+  }
+}
+
+func (search * Search) Elastic(index string, body []byte, schema * Schema) map[string]interface{} {
+  start := time.Now()
+  var elasticBody ElasticBody
+  decoder := json.NewDecoder(bytes.NewReader(body))
+  decoder.DisallowUnknownFields()
+  if err := decoder.Decode(&elasticBody); err != nil && len(bytes.TrimSpace(body)) > 0 {
+    return ElasticError("parsing_exception", err.Error())
+  }
+
+  node := &QueryNode{Operator: "and"}
+  var err error
+  if elasticBody.Query != nil {
+    parser := ElasticParser{Search: search, Schema: schema}
+    if node, err = parser.Parse(elasticBody.Query, "query"); err != nil {
+      return ElasticError("parsing_exception", err.Error())
+    }
+  }
+  keys := make([]SortKey, 0)
+  if elasticBody.Sort != nil {
+    fields, err := DSLSort(elasticBody.Sort)
+    if err != nil {
+      return ElasticError("parsing_exception", err.Error())
+    }
+    if keys, err = search.ParseSort(fields, schema); err != nil {
+      return ElasticError("query_shard_exception", err.Error())
+    }
+  }
+  include, err := ElasticSource(elasticBody.Source)
+  if err != nil {
+    return ElasticError("parsing_exception", err.Error())
+  }
+  from, size := 0, ELASTIC_DEFAULT_SIZE
+  if elasticBody.From != nil {
+    from = *elasticBody.From
+  }
+  if elasticBody.Size != nil {
+    size = *elasticBody.Size
+  }
+  if from < 0 || size < 0 {
+    return ElasticError("illegal_argument_exception", "[from] and [size] can not be negative")
+  }
+  if from + size > ELASTIC_MAX_RESULT_WINDOW {
+    return ElasticError("illegal_argument_exception", fmt.Sprintf("Result window is too large, from + size must be less than or equal to: [%d]", ELASTIC_MAX_RESULT_WINDOW))
+  }
+  if elasticBody.Aggs != nil && elasticBody.Aggregations != nil {
+    return ElasticError("parsing_exception", "aggs and aggregations can not both be given")
+  }
+  if elasticBody.Aggs == nil {
+    elasticBody.Aggs = elasticBody.Aggregations
+  }
+
+  if len(search.TextIndexes) > 0 && schema.TotalItems > 1000000 {
+    search.Lock.Lock()
+    defer search.Lock.Unlock()
+  }
+
+  request := search.NewRequest(nil)
+  results := search.Evaluate(request, schema, node, SearchStart(schema))
+  if len(request.Errors) > 0 {
+    return ElasticError("query_shard_exception", strings.Join(request.Errors, "; "))
+  }
+
+  aggregations := make(map[string]interface{})
+  names := make([]string, 0, len(elasticBody.Aggs))
+  for name, _ := range elasticBody.Aggs {
+    names = append(names, name)
+  }
+  sort.Strings(names)
+  for _, name := range names {
+    aggregation, err := ElasticAggregation(schema, elasticBody.Aggs[name], "aggs." + name, results)
+    if err != nil {
+      return ElasticError("parsing_exception", err.Error())
+    }
+    aggregations[name] = aggregation
+  }
+
+  // scores only count without a sort or when sorting on _score
+  scored := len(keys) == 0
+  for _, key := range keys {
+    scored = scored || key.Field == "_score"
+  }
+  var maxScore interface{}
+  if scored && len(results) > 0 {
+    best := results[0].Score
+    for _, x := range results {
+      if x.Score > best {
+        best = x.Score
+      }
+    }
+    maxScore = best
+  }
+
+  accessors := make([]func(int) (interface{}, bool), len(keys))
+  for k, key := range keys {
+    if key.Field != "_score" {
+      accessors[k] = GenericAccessor(schema.Properties[key.Field])
+    }
+  }
+  sortValue := func(k int, x SearchResult) (interface{}, bool) {
+    if accessors[k] == nil {
+      return x.Score, true
+    }
+    return accessors[k](x.Item)
+  }
+  SortResults(keys, schema, results)
+
+  total := len(results)
+  if from > total {
+    from = total
+  }
+  results = results[from:]
+  if size < len(results) {
+    results = results[:size]
+  }
+  hits := make([]map[string]interface{}, len(results))
+  for i, x := range results {
+    hit := map[string]interface{}{"_index": index, "_id": strconv.Itoa(x.Item), "_score": nil}
+    if scored {
+      hit["_score"] = x.Score
+    }
+    if include != nil {
+      source := make(map[string]interface{})
+      for field, value := range schema.GetItem(x.Item) {
+        if include(field) {
+          source[field] = value
+        }
+      }
+      hit["_source"] = source
+    }
+    if len(keys) > 0 {
+      values := make([]interface{}, len(keys))
+      for k, _ := range keys {
+        if value, has := sortValue(k, x); has {
+          values[k] = value
+        }
+      }
+      hit["sort"] = values
+    }
+    hits[i] = hit
+  }
+
+  output := map[string]interface{}{
+    "took": time.Since(start).Nanoseconds() / int64(time.Millisecond),
+    "timed_out": false,
+    "_shards": map[string]interface{}{"total": 1, "successful": 1, "skipped": 0, "failed": 0},
+    "hits": map[string]interface{}{
+      "total": map[string]interface{}{"value": total, "relation": "eq"},
+      "max_score": maxScore,
+      "hits": hits,
+    },
+  }
+  if elasticBody.Aggs != nil {
+    output["aggregations"] = aggregations
+  }
+  return output
+}
+
+type ElasticParser struct {
+  Search * Search
+  Schema * Schema
+}
+
+// match, term, terms, range, exists, bool and match_all
+func (parser ElasticParser) Parse(query interface{}, path string) (* QueryNode, error) {
+  object, isObject := query.(map[string]interface{})
+  if !isObject || len(object) != 1 {
+    return nil, fmt.Errorf("%s: expected an object with one key", path)
+  }
+  for queryType, value := range object {
+    path += "." + queryType
+    switch queryType {
+      case "match_all":
+        return &QueryNode{Operator: "and"}, nil
+      case "bool":
+        return parser.Bool(value, path)
+      case "exists":
+        params, isObject := value.(map[string]interface{})
+        field, isString := params["field"].(string)
+        if !isObject || !isString {
+          return nil, fmt.Errorf("%s: expected {\"field\": name}", path)
+        }
+        if _, has := parser.Schema.Properties[field]; !has {
+          // unmapped fields exist nowhere
+          return &QueryNode{Operator: "or"}, nil
+        }
+        return ElasticComparison(parser.Schema, field, "null", nil, true, path)
+      case "match", "term", "terms", "range":
+        break;
+      default:
+        return nil, fmt.Errorf("%s: query is not supported", path)
+    }
+
+    params, isObject := value.(map[string]interface{})
+    if !isObject || len(params) != 1 {
+      return nil, fmt.Errorf("%s: expected an object with one field", path)
+    }
+    for field, value := range params {
+      path += "." + field
+      if _, has := parser.Schema.Properties[field]; !has {
+        return nil, fmt.Errorf("%s: unknown field '%s'", path, field)
+      }
+      switch queryType {
+        case "match":
+          return parser.Match(field, value, path)
+        case "term":
+          if object, isObject := value.(map[string]interface{}); isObject {
+            value = object["value"]
+          }
+          return ElasticComparison(parser.Schema, field, "=", []interface{}{value}, false, path)
+        case "terms":
+          values, isList := value.([]interface{})
+          if !isList || len(values) == 0 {
+            return nil, fmt.Errorf("%s: expected a list of values", path)
+          }
+          return ElasticComparison(parser.Schema, field, "in", values, false, path)
+        case "range":
+          return ElasticRange(parser.Schema, field, value, path)
+      }
+    }
+  }
+  return nil, nil
+}
+
+// as BoolQuery; boost is ignored and minimum_should_match may only restate
+// the default of one should clause when there are no must or filter clauses
+func (parser ElasticParser) Bool(value interface{}, path string) (* QueryNode, error) {
+  object, isObject := value.(map[string]interface{})
+  if !isObject {
+    return nil, fmt.Errorf("%s: expected an object", path)
+  }
+  clauses := make(map[string]interface{}, len(object))
+  for key, clause := range object {
+    if key != "boost" && key != "minimum_should_match" {
+      clauses[key] = clause
+    }
+  }
+  if minimum, has := object["minimum_should_match"]; has {
+    required := 0.0
+    if clauses["must"] == nil && clauses["filter"] == nil && clauses["should"] != nil {
+      required = 1
+    }
+    if number, isNumber := minimum.(float64); !isNumber || number != required {
+      return nil, fmt.Errorf("%s.minimum_should_match: only %v is supported here", path, required)
+    }
+  }
+  return BoolQuery(clauses, path, parser.Parse)
+}
+
+// full text fields are searched; any of the words matches unless operator
+// is and. Other fields compare the whole value.
+func (parser ElasticParser) Match(field string, value interface{}, path string) (* QueryNode, error) {
+  operator := "or"
+  if object, isObject := value.(map[string]interface{}); isObject {
+    for key, option := range object {
+      switch key {
+        case "query":
+          value = option
+          break;
+        case "operator":
+          operator, _ = option.(string)
+          operator = strings.ToLower(operator)
+          break;
+        case "boost":
+          break;
+        default:
+          return nil, fmt.Errorf("%s: option '%s' is not supported", path, key)
+      }
+    }
+    if operator != "or" && operator != "and" {
+      return nil, fmt.Errorf("%s.operator: expected or, and", path)
+    }
+  }
+  text, isString := value.(string)
+  if !isString || len(parser.Search.TextFields(field)) == 0 {
+    return ElasticComparison(parser.Schema, field, "=", []interface{}{value}, false, path)
+  }
+  if operator == "and" {
+    return &QueryNode{Field: field, Filter: "search", Value: text}, nil
+  }
+  node := &QueryNode{Operator: "or"}
+  for _, token := range Tokenize(text) {
+    if IsWord(token.Text) {
+      node.Children = append(node.Children, &QueryNode{Field: field, Filter: "search", Value: token.Text})
+    }
+  }
+  if len(node.Children) == 1 {
+    return node.Children[0], nil
+  }
+  return node, nil
+}
+
+// {"gte": a, "lt": b, ...}
+func ElasticRange(schema * Schema, field string, value interface{}, path string) (* QueryNode, error) {
+  object, isObject := value.(map[string]interface{})
+  if !isObject {
+    return nil, fmt.Errorf("%s: expected an object", path)
+  }
+  comparisons := map[string]string{"gt": ">", "gte": ">=", "lt": "<", "lte": "<="}
+  bounds := make([]string, 0, len(object))
+  for bound, _ := range object {
+    bounds = append(bounds, bound)
+  }
+  sort.Strings(bounds)
+  node := &QueryNode{Operator: "and"}
+  for _, bound := range bounds {
+    if bound == "boost" {
+      continue
+    }
+    comparison, has := comparisons[bound]
+    if !has {
+      return nil, fmt.Errorf("%s: option '%s' is not supported", path, bound)
+    }
+    child, err := ElasticComparison(schema, field, comparison, []interface{}{object[bound]}, false, path + "." + bound)
+    if err != nil {
+      return nil, err
+    }
+    node.Children = append(node.Children, child)
+  }
+  if len(node.Children) == 1 {
+    return node.Children[0], nil
+  }
+  return node, nil
+}
+
+// values are coerced to the field type as elasticsearch does
+func ElasticComparison(schema * Schema, field string, filter string, values []interface{}, negate bool, path string) (* QueryNode, error) {
+  fieldType := schema.Properties[field].Type
+  for i, value := range values {
+    if text, isString := value.(string); isString {
+      if number, err := strconv.ParseFloat(text, 64); err == nil && fieldType == "number" {
+        values[i] = number
+      } else if boolean, err := strconv.ParseBool(text); err == nil && fieldType == "boolean" {
+        values[i] = boolean
+      }
+    }
+  }
+  node, err := ComparisonNode(schema, field, filter, values, negate)
+  if err != nil {
+    return nil, fmt.Errorf("%s: %s", path, err.Error())
+  }
+  return node, nil
+}
+
+// nil when _source is false; true, a pattern, a list of patterns or
+// {"includes": ..., "excludes": ...} otherwise
+func ElasticSource(source interface{}) (func(string) bool, error) {
+  patterns := func(value interface{}) ([]string, error) {
+    switch value := value.(type) {
+      case nil:
+        return nil, nil
+      case string:
+        return []string{value}, nil
+      case []interface{}:
+        list := make([]string, len(value))
+        for i, item := range value {
+          pattern, isString := item.(string)
+          if !isString {
+            return nil, fmt.Errorf("_source: expected field names")
+          }
+          list[i] = pattern
+        }
+        return list, nil
+    }
+    return nil, fmt.Errorf("_source: expected field names")
+  }
+  matches := func(patterns []string, field string) bool {
+    for _, pattern := range patterns {
+      if matched, _ := path.Match(pattern, field); matched {
+        return true
+      }
+    }
+    return false
+  }
+
+  var includes, excludes []string
+  var err error
+  switch source := source.(type) {
+    case nil:
+      break;
+    case bool:
+      if !source {
+        return nil, nil
+      }
+      break;
+    case map[string]interface{}:
+      for key, value := range source {
+        switch key {
+          case "includes", "include":
+            includes, err = patterns(value)
+            break;
+          case "excludes", "exclude":
+            excludes, err = patterns(value)
+            break;
+          default:
+            err = fmt.Errorf("_source: option '%s' is not supported", key)
+        }
+        if err != nil {
+          return nil, err
+        }
+      }
+      break;
+    default:
+      if includes, err = patterns(source); err != nil {
+        return nil, err
+      }
+  }
+  return func(field string) bool {
+    return (len(includes) == 0 || matches(includes, field)) && !matches(excludes, field)
+  }, nil
+}
+
+// {"terms": {...}}, {"range": {...}} or {"stats": {...}} over every match
+func ElasticAggregation(schema * Schema, value interface{}, path string, results SearchResults) (map[string]interface{}, error) {
+  object, isObject := value.(map[string]interface{})
+  if !isObject || len(object) != 1 {
+    if _, has := object["aggs"]; has {
+      return nil, fmt.Errorf("%s: sub aggregations are not supported", path)
+    }
+    return nil, fmt.Errorf("%s: expected an object with one key", path)
+  }
+  for aggregationType, value := range object {
+    path += "." + aggregationType
+    params, isObject := value.(map[string]interface{})
+    field, isString := params["field"].(string)
+    if !isObject || !isString {
+      return nil, fmt.Errorf("%s: expected a field", path)
+    }
+    fieldData, has := schema.Properties[field]
+    if !has {
+      return nil, fmt.Errorf("%s: unknown field '%s'", path, field)
+    }
+    switch aggregationType {
+      case "terms":
+        if fieldData.Type != "string" && fieldData.Type != "number" && fieldData.Type != "boolean" {
+          return nil, fmt.Errorf("%s: terms needs a string, number or boolean field; '%s' is %s", path, field, fieldData.Type)
+        }
+        size := ELASTIC_DEFAULT_SIZE
+        if number, isNumber := params["size"].(float64); isNumber {
+          size = int(number)
+        }
+        return ElasticTerms(schema, field, size, results)
+      case "range", "stats":
+        if fieldData.Type != "number" {
+          return nil, fmt.Errorf("%s: %s needs a number field; '%s' is %s", path, aggregationType, field, fieldData.Type)
+        }
+        if aggregationType == "stats" {
+          return ElasticStats(schema, field, results), nil
+        }
+        ranges, isList := params["ranges"].([]interface{})
+        if !isList {
+          return nil, fmt.Errorf("%s: expected ranges", path)
+        }
+        return ElasticRanges(schema, field, ranges, path, results)
+    }
+    return nil, fmt.Errorf("%s: aggregation is not supported", path)
+  }
+  return nil, nil
+}
+
+// most frequent values first
+func ElasticTerms(schema * Schema, field string, size int, results SearchResults) (map[string]interface{}, error) {
+  groups, err := GroupBy(schema, []string{field}, nil, results)
+  if err != nil {
+    return nil, err
+  }
+  buckets := make([]map[string]interface{}, 0, len(groups))
+  for _, group := range groups {
+    if group.Codes[0] != -1 {
+      buckets = append(buckets, map[string]interface{}{"key": group.Values(schema, []string{field})[0], "doc_count": group.Count})
+    }
+  }
+  sort.SliceStable(buckets, func(i, j int) bool {
+    if buckets[i]["doc_count"] != buckets[j]["doc_count"] {
+      return buckets[i]["doc_count"].(int) > buckets[j]["doc_count"].(int)
+    }
+    return CompareValues(buckets[i]["key"], buckets[j]["key"]) < 0
+  })
+  other := 0
+  if size < len(buckets) {
+    for _, bucket := range buckets[size:] {
+      other += bucket["doc_count"].(int)
+    }
+    buckets = buckets[:size]
+  }
+  return map[string]interface{}{"doc_count_error_upper_bound": 0, "sum_other_doc_count": other, "buckets": buckets}, nil
+}
+
+// from is inclusive, to exclusive
+func ElasticRanges(schema * Schema, field string, ranges []interface{}, path string, results SearchResults) (map[string]interface{}, error) {
+  getValue := NumberAccessor(schema.Properties[field])
+  buckets := make([]map[string]interface{}, len(ranges))
+  for i, item := range ranges {
+    params, isObject := item.(map[string]interface{})
+    if !isObject {
+      return nil, fmt.Errorf("%s.ranges[%d]: expected an object", path, i)
+    }
+    from, hasFrom := params["from"].(float64)
+    to, hasTo := params["to"].(float64)
+    key, hasKey := params["key"].(string)
+    if !hasKey {
+      key = "*-"
+      if hasFrom {
+        key = ElasticNumber(from) + "-"
+      }
+      if hasTo {
+        key += ElasticNumber(to)
+      } else {
+        key += "*"
+      }
+    }
+    count := 0
+    for _, x := range results {
+      if value, has := getValue(x.Item); has && (!hasFrom || value >= from) && (!hasTo || value < to) {
+        count++
+      }
+    }
+    bucket := map[string]interface{}{"key": key, "doc_count": count}
+    if hasFrom {
+      bucket["from"] = from
+    }
+    if hasTo {
+      bucket["to"] = to
+    }
+    buckets[i] = bucket
+  }
+  return map[string]interface{}{"buckets": buckets}, nil
+}
+
+func ElasticStats(schema * Schema, field string, results SearchResults) map[string]interface{} {
+  getValue := NumberAccessor(schema.Properties[field])
+  var state AggregateState
+  for _, x := range results {
+    if value, has := getValue(x.Item); has {
+      state.Add(value)
+    }
+  }
+  return map[string]interface{}{
+    "count": state.Count,
+    "min": state.Value("min"),
+    "max": state.Value("max"),
+    "avg": state.Value("avg"),
+    "sum": state.Sum,
+  }
+}
+
+// numbers in bucket keys keep a decimal point, e.g. 50.0
+func ElasticNumber(number float64) string {
+  text := strconv.FormatFloat(number, 'f', -1, 64)
+  if !strings.ContainsAny(text, ".eE") {
+    text += ".0"
+  }
+  return text
+}
@@ -0,0 +1,218 @@
+package index
+
+import (
+  "reflect"
+  "testing"
+)
+
+// total hits of a query body, or the error reason
+func ElasticTotal(output map[string]interface{}) (int, string) {
+  if err, has := output["error"].(map[string]interface{}); has {
+    return -1, err["reason"].(string)
+  }
+  return output["hits"].(map[string]interface{})["total"].(map[string]interface{})["value"].(int), ""
+}
+
+func TestElasticQuery(t *testing.T) {
+  collection := FixtureCollection()
+  tests := []struct {
+    query string
+    total int
+    err string
+  }{
+    {`{"match_all": {}}`, 400, ""},
+    // any word matches unless the operator is and
+    {`{"match": {"title": "television remote"}}`, 134, ""},
+    {`{"match": {"title": "television item"}}`, 400, ""},
+    {`{"match": {"title": {"query": "television item", "operator": "AND"}}}`, 0, ""},
+    {`{"match": {"title": {"query": "television remote", "operator": "and"}}}`, 134, ""},
+    {`{"match": {"color": "red"}}`, 134, ""},
+    // strings are coerced to the field type
+    {`{"term": {"price": "5"}}`, 7, ""},
+    {`{"term": {"price": {"value": 5}}}`, 7, ""},
+    {`{"term": {"flag": "true"}}`, 200, ""},
+    {`{"terms": {"brand": ["acme", "globex"]}}`, 200, ""},
+    {`{"terms": {"price": ["1", 2]}}`, 14, ""},
+    {`{"range": {"price": {"gte": 10, "lt": 20}}}`, 69, ""},
+    {`{"range": {"price": {"gt": "10", "lte": 20, "boost": 2}}}`, 69, ""},
+    {`{"exists": {"field": "price"}}`, 342, ""},
+    {`{"exists": {"field": "nope"}}`, 0, ""},
+    {`{"bool": {"must_not": {"exists": {"field": "price"}}}}`, 58, ""},
+    {`{"bool": {"filter": [{"term": {"flag": true}}, {"exists": {"field": "price"}}]}}`, 171, ""},
+    {`{"bool": {"should": [{"term": {"color": "red"}}, {"term": {"color": "blue"}}], "minimum_should_match": 1}}`, 267, ""},
+    {`{"bool": {"filter": {"term": {"color": "red"}}, "should": {"term": {"brand": "acme"}}, "minimum_should_match": 0}}`, 134, ""},
+    {`{"bool": {"filter": {"term": {"color": "red"}}, "should": {"term": {"brand": "acme"}}, "minimum_should_match": 1}}`, -1, "query.bool.minimum_should_match: only 0 is supported here"},
+    {`{"bool": {"should": {"term": {"color": "red"}}, "minimum_should_match": 2}}`, -1, "query.bool.minimum_should_match: only 1 is supported here"},
+    {`{"match": {"title": {"query": "tv", "operator": "xor"}}}`, -1, "query.match.title.operator: expected or, and"},
+    {`{"match": {"title": {"query": "tv", "fuzziness": 1}}}`, -1, "query.match.title: option 'fuzziness' is not supported"},
+    {`{"term": {"nope": 1}}`, -1, "query.term.nope: unknown field 'nope'"},
+    {`{"terms": {"brand": []}}`, -1, "query.terms.brand: expected a list of values"},
+    {`{"range": {"price": {"from": 1}}}`, -1, "query.range.price: option 'from' is not supported"},
+    {`{"exists": "price"}`, -1, "query.exists: expected {\"field\": name}"},
+    {`{"wildcard": {"title": "tele*"}}`, -1, "query.wildcard: query is not supported"},
+  }
+  for _, test := range tests {
+    output := collection.search.Elastic("items", []byte(`{"size": 0, "query": ` + test.query + `}`), collection.schema)
+    if total, err := ElasticTotal(output); total != test.total || err != test.err {
+      t.Errorf("%s: total = %d, error = %q, want %d, %q", test.query, total, err, test.total, test.err)
+    }
+    if status, has := output["status"]; has != (test.err != "") || (has && status != 400) {
+      t.Errorf("%s: status = %v", test.query, status)
+    }
+  }
+}
+
+func TestElasticSource(t *testing.T) {
+  fields := []string{"brand", "color", "flag", "price", "title"}
+  tests := []struct {
+    source interface{}
+    want []string // nil when there is no _source
+    err string
+  }{
+    {nil, fields, ""},
+    {true, fields, ""},
+    {false, nil, ""},
+    {"t*", []string{"title"}, ""},
+    {[]interface{}{"color", "b*"}, []string{"brand", "color"}, ""},
+    {map[string]interface{}{"includes": "*", "excludes": []interface{}{"t*", "flag"}}, []string{"brand", "color", "price"}, ""},
+    {map[string]interface{}{"include": []interface{}{"?rice", "color"}, "exclude": "c*"}, []string{"price"}, ""},
+    {map[string]interface{}{"excludes": "*"}, []string{}, ""},
+    {5.0, nil, "_source: expected field names"},
+    {[]interface{}{"title", 1.0}, nil, "_source: expected field names"},
+    {map[string]interface{}{"fields": "title"}, nil, "_source: option 'fields' is not supported"},
+  }
+  for _, test := range tests {
+    include, err := ElasticSource(test.source)
+    if test.err != "" || err != nil {
+      if err == nil || err.Error() != test.err {
+        t.Errorf("ElasticSource(%v) error = %v, want %q", test.source, err, test.err)
+      }
+      continue
+    }
+    var got []string
+    if include != nil {
+      got = make([]string, 0)
+      for _, field := range fields {
+        if include(field) {
+          got = append(got, field)
+        }
+      }
+    }
+    if !reflect.DeepEqual(got, test.want) {
+      t.Errorf("ElasticSource(%v) includes %q, want %q", test.source, got, test.want)
+    }
+  }
+}
+
+func TestElasticWindow(t *testing.T) {
+  collection := FixtureCollection()
+  tests := []struct {
+    body string
+    hits int
+    err string
+  }{
+    {`{}`, 10, ""},
+    {``, 10, ""},
+    {`{"from": 395}`, 5, ""},
+    {`{"from": 500, "size": 5}`, 0, ""},
+    {`{"from": 9990, "size": 10}`, 0, ""},
+    {`{"from": 9990, "size": 11}`, -1, "Result window is too large, from + size must be less than or equal to: [10000]"},
+    {`{"size": 10001}`, -1, "Result window is too large, from + size must be less than or equal to: [10000]"},
+    {`{"from": -1}`, -1, "[from] and [size] can not be negative"},
+    {`{"size": -1}`, -1, "[from] and [size] can not be negative"},
+  }
+  for _, test := range tests {
+    output := collection.search.Elastic("items", []byte(test.body), collection.schema)
+    hits, err := -1, ""
+    if _, reason := ElasticTotal(output); reason != "" {
+      err = reason
+    } else {
+      hits = len(output["hits"].(map[string]interface{})["hits"].([]map[string]interface{}))
+    }
+    if hits != test.hits || err != test.err {
+      t.Errorf("%s: %d hits, error %q, want %d, %q", test.body, hits, err, test.hits, test.err)
+    }
+  }
+}
+
+// hits carry their sort values; scores only without a sort or sorting on _score
+func TestElasticSort(t *testing.T) {
+  collection := FixtureCollection()
+  output := collection.search.Elastic("products", []byte(`{"sort": [{"price": "desc"}, "popularity"], "size": 2, "_source": ["price"]}`), collection.schema)
+  hits := output["hits"].(map[string]interface{})
+  want := []map[string]interface{}{
+    {"_index": "products", "_id": "99", "_score": nil, "_source": map[string]interface{}{"price": 49.0}, "sort": []interface{}{49.0, 99.0}},
+    {"_index": "products", "_id": "149", "_score": nil, "_source": map[string]interface{}{"price": 49.0}, "sort": []interface{}{49.0, 149.0}},
+  }
+  if !reflect.DeepEqual(hits["hits"], want) || hits["max_score"] != nil {
+    t.Errorf("hits = %v, max_score %v, want %v", hits["hits"], hits["max_score"], want)
+  }
+
+  // missing values sort last and have no sort value
+  output = collection.search.Elastic("items", []byte(`{"sort": [{"price": "desc"}, {"popularity": "desc"}], "from": 341, "size": 2, "_source": false}`), collection.schema)
+  want = []map[string]interface{}{
+    {"_index": "items", "_id": "50", "_score": nil, "sort": []interface{}{0.0, 50.0}},
+    {"_index": "items", "_id": "399", "_score": nil, "sort": []interface{}{nil, 399.0}},
+  }
+  hits = output["hits"].(map[string]interface{})
+  if !reflect.DeepEqual(hits["hits"], want) {
+    t.Errorf("hits = %v, want %v", hits["hits"], want)
+  }
+
+  output = collection.search.Elastic("items", []byte(`{"query": {"match": {"title": "television"}}, "sort": ["_score"], "size": 1, "_source": false}`), collection.schema)
+  hits = output["hits"].(map[string]interface{})
+  hit := hits["hits"].([]map[string]interface{})[0]
+  if score, isNumber := hit["_score"].(float64); !isNumber || score <= 0 || hits["max_score"] != score || !reflect.DeepEqual(hit["sort"], []interface{}{score}) {
+    t.Errorf("hit = %v, max_score %v", hit, hits["max_score"])
+  }
+}
+
+func TestElasticAggregations(t *testing.T) {
+  collection := FixtureCollection()
+  tests := []struct {
+    aggs string
+    want map[string]interface{}
+    err string
+  }{
+    {`{"colors": {"terms": {"field": "color", "size": 2}}}`, map[string]interface{}{"colors": map[string]interface{}{
+      "doc_count_error_upper_bound": 0, "sum_other_doc_count": 133, "buckets": []map[string]interface{}{
+        {"key": "red", "doc_count": 134},
+        {"key": "blue", "doc_count": 133},
+      },
+    }}, ""},
+    {`{"flags": {"terms": {"field": "flag"}}}`, map[string]interface{}{"flags": map[string]interface{}{
+      "doc_count_error_upper_bound": 0, "sum_other_doc_count": 0, "buckets": []map[string]interface{}{
+        {"key": false, "doc_count": 200},
+        {"key": true, "doc_count": 200},
+      },
+    }}, ""},
+    {`{"prices": {"range": {"field": "price", "ranges": [{"to": 10}, {"from": 10, "to": 20}, {"key": "high", "from": 40}]}}}`, map[string]interface{}{"prices": map[string]interface{}{
+      "buckets": []map[string]interface{}{
+        {"key": "*-10.0", "to": 10.0, "doc_count": 68},
+        {"key": "10.0-20.0", "from": 10.0, "to": 20.0, "doc_count": 69},
+        {"key": "high", "from": 40.0, "doc_count": 68},
+      },
+    }}, ""},
+    {`{"price": {"stats": {"field": "price"}}}`, map[string]interface{}{"price": map[string]interface{}{
+      "count": 342, "min": 0.0, "max": 49.0, "avg": 8379.0 / 342, "sum": 8379.0,
+    }}, ""},
+    {`{"x": {"stats": {"field": "color"}}}`, nil, "aggs.x.stats: stats needs a number field; 'color' is string"},
+    {`{"x": {"range": {"field": "price"}}}`, nil, "aggs.x.range: expected ranges"},
+    {`{"x": {"terms": {"field": "nope"}}}`, nil, "aggs.x.terms: unknown field 'nope'"},
+    {`{"x": {"avg": {"field": "price"}}}`, nil, "aggs.x.avg: aggregation is not supported"},
+    {`{"x": {"terms": {"field": "color"}, "aggs": {}}}`, nil, "aggs.x: sub aggregations are not supported"},
+  }
+  for _, test := range tests {
+    output := collection.search.Elastic("items", []byte(`{"size": 0, "aggs": ` + test.aggs + `}`), collection.schema)
+    if _, err := ElasticTotal(output); err != test.err || (err == "" && !reflect.DeepEqual(output["aggregations"], test.want)) {
+      t.Errorf("%s = %v, error %q, want %v, %q", test.aggs, output["aggregations"], err, test.want, test.err)
+    }
+  }
+
+  // aggregations count every match, not only the page
+  output := collection.search.Elastic("items", []byte(`{"size": 1, "query": {"term": {"color": "red"}}, "aggregations": {"c": {"terms": {"field": "color"}}}}`), collection.schema)
+  buckets := output["aggregations"].(map[string]interface{})["c"].(map[string]interface{})["buckets"]
+  if want := []map[string]interface{}{{"key": "red", "doc_count": 134}}; !reflect.DeepEqual(buckets, want) {
+    t.Errorf("buckets = %v, want %v", buckets, want)
+  }
+}
@@ -0,0 +1,172 @@
+package index
+
+import (
+  "fmt"
+  "net/url"
+  "sort"
+  "strconv"
+  "strings"
+)
+
+const FACET_SIZE int = 10
+
+// facets=color,brand:5,price:range:0,50,100
+// counts of the top values, or of number ranges between the bounds, among
+// the matching records
+type Facet struct {
+  Field string
+  Size int
+  Bounds []float64 // range facets only
+  Range bool
+}
+
+func (search * Search) ParseFacets(value string, schema * Schema) ([]Facet, error) {
+  facets := make([]Facet, 0)
+  for _, item := range strings.Split(value, ",") {
+    item = strings.TrimSpace(item)
+    if item == "" {
+      continue
+    }
+    // further bounds of the range before
+    if last := len(facets) - 1; last >= 0 && facets[last].Range {
+      if bound, err := strconv.ParseFloat(item, 64); err == nil {
+        facets[last].Bounds = append(facets[last].Bounds, bound)
+        continue
+      }
+    }
+    parts := strings.SplitN(item, ":", 3)
+    facet := Facet{Field: parts[0], Size: FACET_SIZE}
+    fieldData, has := schema.Properties[facet.Field]
+    if !has || !search.Searchable(facet.Field) {
+      return nil, fmt.Errorf("facet field '%s' does not exist", facet.Field)
+    }
+    if !Sortable(fieldData) {
+      return nil, fmt.Errorf("facet field '%s' is %s and can not be counted", facet.Field, fieldData.Type)
+    }
+    for _, other := range facets {
+      if other.Field == facet.Field {
+        return nil, fmt.Errorf("facet field '%s' is given twice", facet.Field)
+      }
+    }
+    if len(parts) > 1 && parts[1] == "range" {
+      if fieldData.Type != "number" {
+        return nil, fmt.Errorf("range facet field '%s' is %s, not number", facet.Field, fieldData.Type)
+      }
+      facet.Range = true
+      if len(parts) > 2 {
+        bound, err := strconv.ParseFloat(parts[2], 64)
+        if err != nil {
+          return nil, fmt.Errorf("facet range bound '%s' is not a number", parts[2])
+        }
+        facet.Bounds = append(facet.Bounds, bound)
+      }
+    } else if len(parts) > 1 {
+      size, err := strconv.Atoi(strings.Join(parts[1:], ":"))
+      if err != nil || size <= 0 {
+        return nil, fmt.Errorf("facet size '%s' is not a positive integer", strings.Join(parts[1:], ":"))
+      }
+      facet.Size = size
+    }
+    facets = append(facets, facet)
+  }
+  for _, facet := range facets {
+    if !facet.Range {
+      continue
+    }
+    if len(facet.Bounds) < 2 {
+      return nil, fmt.Errorf("range facet '%s' needs two or more bounds, e.g. %s:range:0,50,100", facet.Field, facet.Field)
+    }
+    if !sort.Float64sAreSorted(facet.Bounds) {
+      return nil, fmt.Errorf("range facet '%s' bounds must be in ascending order", facet.Field)
+    }
+  }
+  return facets, nil
+}
+
+// each facet over results; with exclude, over the records matching every
+// filter parameter except those of its own field
+func (search * Search) Facets(request * SearchRequest, schema * Schema, results SearchResults) map[string]interface{} {
+  facets, err := search.ParseFacets(strings.Join(request.Queries["facets"], ","), schema)
+  if err != nil {
+    request.Errors = append(request.Errors, err.Error())
+    return nil
+  }
+  exclude := request.Queries.Get("facet_exclude") == "true"
+  output := make(map[string]interface{}, len(facets))
+  for _, facet := range facets {
+    records := results
+    if _, filtered := request.Queries[facet.Field]; exclude && filtered {
+      queries := make(url.Values, len(request.Queries))
+      for key, value := range request.Queries {
+        if key != facet.Field {
+          queries[key] = value
+        }
+      }
+      records = search.Match(&SearchRequest{Queries: queries, Boosts: request.Boosts, HighlightTerms: make(HighlightTerms), Knn: request.Knn, Query: request.Query}, schema)
+    }
+    output[facet.Field] = facet.Count(schema, records)
+  }
+  return output
+}
+
+// counts dictionary codes, so each distinct value is looked at once
+func (facet Facet) Count(schema * Schema, results SearchResults) map[string]interface{} {
+  fieldData := schema.Properties[facet.Field]
+  counts := make([]int, len(fieldData.UniqueValues))
+  missing := 0
+  for _, x := range results {
+    if code := fieldData.ValueIndex[x.Item]; code != -1 {
+      counts[code]++
+    } else {
+      missing++
+    }
+  }
+  other := 0
+  if facet.Range {
+    buckets := make([]map[string]interface{}, len(facet.Bounds) - 1)
+    totals := make([]int, len(buckets))
+    for code, count := range counts {
+      value := fieldData.UniqueValues[code].(float64)
+      // bucket i holds bounds[i] <= value < bounds[i + 1]
+      i := sort.SearchFloat64s(facet.Bounds, value)
+      if i < len(facet.Bounds) && facet.Bounds[i] == value {
+        i++
+      }
+      if i == 0 || i == len(facet.Bounds) {
+        other += count
+      } else {
+        totals[i - 1] += count
+      }
+    }
+    for i := range buckets {
+      from, to := facet.Bounds[i], facet.Bounds[i + 1]
+      buckets[i] = map[string]interface{}{
+        "from": from,
+        "to": to,
+        "count": totals[i],
+        "filter": "between:[" + strconv.FormatFloat(from, 'f', -1, 64) + "," + strconv.FormatFloat(to, 'f', -1, 64) + ")",
+      }
+    }
+    return map[string]interface{}{"ranges": buckets, "missing": missing, "other": other}
+  }
+  codes := make([]int, 0, len(counts))
+  for code, count := range counts {
+    if count > 0 {
+      codes = append(codes, code)
+    }
+  }
+  sort.SliceStable(codes, func(i, j int) bool {
+    return counts[codes[i]] > counts[codes[j]]
+  })
+  if len(codes) > facet.Size {
+    for _, code := range codes[facet.Size:] {
+      other += counts[code]
+    }
+    codes = codes[:facet.Size]
+  }
+  values := make([]map[string]interface{}, len(codes))
+  for i, code := range codes {
+    values[i] = map[string]interface{}{"value": fieldData.UniqueValues[code], "count": counts[code]}
+  }
+  return map[string]interface{}{"values": values, "missing": missing, "other": other}
+}
@@ -0,0 +1,140 @@
+package index
+
+import (
+  "net/url"
+  "reflect"
+  "testing"
+)
+
+type Buckets []map[string]interface{}
+
+func TestParseFacets(t *testing.T) {
+  collection := FixtureCollection()
+  tests := []struct {
+    value string
+    want []Facet
+    err string
+  }{
+    {"color", []Facet{{Field: "color", Size: 10}}, ""},
+    {"color,brand:2,price:range:0,10,20", []Facet{{Field: "color", Size: 10}, {Field: "brand", Size: 2}, {Field: "price", Size: 10, Bounds: []float64{0, 10, 20}, Range: true}}, ""},
+    {"price:range:0,10, color", []Facet{{Field: "price", Size: 10, Bounds: []float64{0, 10}, Range: true}, {Field: "color", Size: 10}}, ""},
+    {"price:range:-5,0,0.5", []Facet{{Field: "price", Size: 10, Bounds: []float64{-5, 0, 0.5}, Range: true}}, ""},
+    {"", []Facet{}, ""},
+    {"nope", nil, "facet field 'nope' does not exist"},
+    {"color,color:5", nil, "facet field 'color' is given twice"},
+    {"color:0", nil, "facet size '0' is not a positive integer"},
+    {"color:many", nil, "facet size 'many' is not a positive integer"},
+    {"color:range:0,10", nil, "range facet field 'color' is string, not number"},
+    {"price:range:0", nil, "range facet 'price' needs two or more bounds, e.g. price:range:0,50,100"},
+    {"price:range", nil, "range facet 'price' needs two or more bounds, e.g. price:range:0,50,100"},
+    {"price:range:10,0", nil, "range facet 'price' bounds must be in ascending order"},
+    {"price:range:x", nil, "facet range bound 'x' is not a number"},
+  }
+  for _, test := range tests {
+    facets, err := collection.search.ParseFacets(test.value, collection.schema)
+    if err != nil {
+      if err.Error() != test.err {
+        t.Errorf("ParseFacets(%q) error = %v, want %q", test.value, err, test.err)
+      }
+    } else if test.err != "" || !reflect.DeepEqual(facets, test.want) {
+      t.Errorf("ParseFacets(%q) = %+v, want %+v, error %q", test.value, facets, test.want, test.err)
+    }
+  }
+}
+
+func TestFacetCount(t *testing.T) {
+  schema := FixtureCollection().schema
+  // price is the record id modulo 50, missing every seventh record; colors
+  // go red, blue, green
+  items := func(ids ...int) SearchResults {
+    results := make(SearchResults, len(ids))
+    for i, id := range ids {
+      results[i].Item = id
+    }
+    return results
+  }
+  tests := []struct {
+    facet Facet
+    results SearchResults
+    missing int
+    other int
+    buckets Buckets // ranges or values
+  }{
+    {
+      Facet{Field: "price", Range: true, Bounds: []float64{0, 10, 20}},
+      items(1, 9, 10, 19, 20, 21, 49, 50), 2, 1,
+      Buckets{
+        {"count": 3, "filter": "between:[0,10)", "from": 0.0, "to": 10.0},
+        {"count": 2, "filter": "between:[10,20)", "from": 10.0, "to": 20.0},
+      },
+    },
+    // a value equal to a bound goes to the bucket that starts at it
+    {
+      Facet{Field: "price", Range: true, Bounds: []float64{5, 10, 10.5}},
+      items(4, 5, 10, 11), 0, 2,
+      Buckets{
+        {"count": 1, "filter": "between:[5,10)", "from": 5.0, "to": 10.0},
+        {"count": 1, "filter": "between:[10,10.5)", "from": 10.0, "to": 10.5},
+      },
+    },
+    {
+      Facet{Field: "price", Range: true, Bounds: []float64{0, 10}},
+      items(), 0, 0,
+      Buckets{{"count": 0, "filter": "between:[0,10)", "from": 0.0, "to": 10.0}},
+    },
+    {
+      Facet{Field: "color", Size: 10},
+      items(0, 1, 2, 3, 4, 6), 0, 0,
+      Buckets{{"count": 3, "value": "red"}, {"count": 2, "value": "blue"}, {"count": 1, "value": "green"}},
+    },
+    // ties keep dictionary order
+    {
+      Facet{Field: "color", Size: 2},
+      items(0, 1, 2, 3, 4, 5), 0, 2,
+      Buckets{{"count": 2, "value": "blue"}, {"count": 2, "value": "green"}},
+    },
+    {
+      Facet{Field: "price", Size: 1},
+      items(0, 1, 51, 7), 2, 0,
+      Buckets{{"count": 2, "value": 1.0}},
+    },
+    {
+      Facet{Field: "color", Size: 10},
+      items(), 0, 0,
+      Buckets{},
+    },
+  }
+  for _, test := range tests {
+    output := test.facet.Count(schema, test.results)
+    key := "values"
+    if test.facet.Range {
+      key = "ranges"
+    }
+    buckets, _ := output[key].([]map[string]interface{})
+    if output["missing"] != test.missing || output["other"] != test.other || !reflect.DeepEqual(Buckets(buckets), test.buckets) {
+      t.Errorf("%+v.Count(%v) = %v, want missing %d, other %d, %s %v", test.facet, test.results, output, test.missing, test.other, key, test.buckets)
+    }
+  }
+}
+
+// with facet_exclude the color facet ignores the color filter but not others
+func TestFacetExclude(t *testing.T) {
+  collection := FixtureCollection()
+  tests := []struct {
+    exclude string
+    want Buckets
+  }{
+    {"false", Buckets{{"count": 34, "value": "red"}}},
+    {"true", Buckets{{"count": 34, "value": "red"}, {"count": 33, "value": "blue"}, {"count": 33, "value": "green"}}},
+  }
+  for _, test := range tests {
+    queries := url.Values{"color": {"red"}, "brand": {"acme"}, "facets": {"color"}, "facet_exclude": {test.exclude}}
+    output := collection.search.Search(queries, collection.schema)
+    facets, _ := output["facets"].(map[string]interface{})
+    color, _ := facets["color"].(map[string]interface{})
+    values, _ := color["values"].([]map[string]interface{})
+    if color["missing"] != 0 || color["other"] != 0 || !reflect.DeepEqual(Buckets(values), test.want) {
+      t.Errorf("facet_exclude=%s: color = %v, want values %v", test.exclude, color, test.want)
+    }
+  }
+}
@@ -0,0 +1,370 @@
+package index
+
+import (
+  "fmt"
+  "math"
+  "net/url"
+  "sort"
+  "strconv"
+  "strings"
+)
+
+var AGGREGATE_FUNCTIONS = []string{"count", "sum", "avg", "min", "max", "stddev"}
+
+// function over a number field; count of "" counts records
+type Aggregate struct {
+  Function string
+  Field string
+}
+
+func (aggregate Aggregate) Name() string {
+  field := aggregate.Field
+  if field == "" {
+    field = "*"
+  }
+  return aggregate.Function + "(" + field + ")"
+}
+
+func (aggregate Aggregate) Check(schema * Schema) error {
+  supported := false
+  for _, function := range AGGREGATE_FUNCTIONS {
+    supported = supported || function == aggregate.Function
+  }
+  if !supported {
+    return fmt.Errorf("aggregate '%s' is not supported", aggregate.Function)
+  }
+  if aggregate.Field == "" {
+    if aggregate.Function != "count" {
+      return fmt.Errorf("%s needs a field", aggregate.Function)
+    }
+    return nil
+  }
+  field, has := schema.Properties[aggregate.Field]
+  if !has {
+    return fmt.Errorf("field '%s' does not exist", aggregate.Field)
+  }
+  if aggregate.Function != "count" && field.Type != "number" {
+    return fmt.Errorf("%s needs a number field; '%s' is %s", aggregate.Function, aggregate.Field, field.Type)
+  }
+  return nil
+}
+
+type AggregateState struct {
+  Count int
+  Sum float64
+  Min float64
+  Max float64
+  SumSquares float64
+}
+
+func (state * AggregateState) Add(value float64) {
+  state.AddCount(value, 1)
+}
+
+// count records with the same value
+func (state * AggregateState) AddCount(value float64, count int) {
+  if state.Count == 0 || value < state.Min {
+    state.Min = value
+  }
+  if state.Count == 0 || value > state.Max {
+    state.Max = value
+  }
+  state.Count += count
+  state.Sum += value * float64(count)
+  state.SumSquares += value * value * float64(count)
+}
+
+// nil when there were no values
+func (state AggregateState) Value(function string) interface{} {
+  if function == "count" {
+    return state.Count
+  }
+  if state.Count == 0 {
+    return nil
+  }
+  switch function {
+    case "sum":
+      return state.Sum
+    case "avg":
+      return state.Sum / float64(state.Count)
+    case "min":
+      return state.Min
+    case "max":
+      return state.Max
+    case "stddev":
+      // population standard deviation
+      mean := state.Sum / float64(state.Count)
+      return math.Sqrt(math.Max(0, state.SumSquares / float64(state.Count) - mean * mean))
+  }
+  return nil
+}
+
+// records sharing the dictionary codes of the grouping fields
+type GroupRow struct {
+  Codes []int
+  Count int
+  States []AggregateState
+}
+
+// groups in order of first appearance
+func GroupBy(schema * Schema, fields []string, aggregates []Aggregate, results SearchResults) ([]*GroupRow, error) {
+  valueIndexes := make([][]int, len(fields))
+  for i, field := range fields {
+    fieldData, has := schema.Properties[field]
+    if !has {
+      return nil, fmt.Errorf("field '%s' does not exist", field)
+    }
+    valueIndexes[i] = fieldData.ValueIndex
+  }
+  accessors := make([]func(int) (interface{}, bool), len(aggregates))
+  for a, aggregate := range aggregates {
+    if err := aggregate.Check(schema); err != nil {
+      return nil, err
+    }
+    if aggregate.Field != "" {
+      accessors[a] = GenericAccessor(schema.Properties[aggregate.Field])
+    }
+  }
+
+  groups := make([]*GroupRow, 0)
+  lookup := make(map[string]*GroupRow)
+  key := make([]string, len(fields))
+  for _, x := range results {
+    for i, valueIndex := range valueIndexes {
+      key[i] = strconv.Itoa(valueIndex[x.Item])
+    }
+    group, has := lookup[strings.Join(key, ",")]
+    if !has {
+      group = &GroupRow{Codes: make([]int, len(fields)), States: make([]AggregateState, len(aggregates))}
+      for i, valueIndex := range valueIndexes {
+        group.Codes[i] = valueIndex[x.Item]
+      }
+      lookup[strings.Join(key, ",")] = group
+      groups = append(groups, group)
+    }
+    group.Count++
+    for a, accessor := range accessors {
+      if accessor == nil {
+        group.States[a].Count++
+      } else if value, has := accessor(x.Item); has {
+        if number, isNumber := value.(float64); isNumber {
+          group.States[a].Add(number)
+        } else {
+          group.States[a].Count++
+        }
+      }
+    }
+  }
+  return groups, nil
+}
+
+// values of the grouping fields; nil when missing
+func (group * GroupRow) Values(schema * Schema, fields []string) []interface{} {
+  values := make([]interface{}, len(fields))
+  for i, field := range fields {
+    if code := group.Codes[i]; code != -1 {
+      values[i] = schema.Properties[field].UniqueValues[code]
+    }
+  }
+  return values
+}
+
+// order of json values of mixed types: missing, booleans, numbers, strings, others
+func CompareValues(a, b interface{}) int {
+  rank := func(value interface{}) int {
+    switch value.(type) {
+      case nil:
+        return 0
+      case bool:
+        return 1
+      case float64, int:
+        return 2
+      case string:
+        return 3
+    }
+    return 4
+  }
+  if rank(a) != rank(b) {
+    return rank(a) - rank(b)
+  }
+  switch a := a.(type) {
+    case bool:
+      if a == b.(bool) {
+        return 0
+      } else if !a {
+        return -1
+      }
+      return 1
+    case float64, int:
+      x, y := ToFloat(a), ToFloat(b)
+      if x < y {
+        return -1
+      } else if x > y {
+        return 1
+      }
+      return 0
+    case string:
+      return strings.Compare(a, b.(string))
+  }
+  return strings.Compare(ToJson(a), ToJson(b))
+}
+
+func ToFloat(value interface{}) float64 {
+  switch value := value.(type) {
+    case float64:
+      return value
+    case int:
+      return float64(value)
+  }
+  return math.NaN()
+}
+
+// sorts groups by a grouping field, by code, or by an aggregate; the other
+// index is -1
+type GroupSort struct {
+  Field int
+  Aggregate int
+  Descending bool
+}
+
+// by=country,category&agg=sum:revenue,avg:price&sort=-sum(revenue)
+// one row per combination of values of the by fields among the matching
+// records, with count(*) and the agg aggregates. Rows sort by count(*) unless
+// sort names by fields or aggregates; missing values sort last.
+func (search * Search) Groups(queries url.Values, schema * Schema) map[string]interface{} {
+  if len(search.TextIndexes) > 0 && schema.TotalItems > 1000000 {
+    search.Lock.Lock()
+    defer search.Lock.Unlock()
+  }
+
+  request := search.NewRequest(queries)
+  fields := make([]string, 0)
+  for _, field := range strings.Split(strings.Join(queries["by"], ","), ",") {
+    if field = strings.TrimSpace(field); field == "" {
+      continue
+    }
+    if fieldData, has := schema.Properties[field]; !has || !search.Searchable(field) {
+      request.Errors = append(request.Errors, "field '" + field + "' does not exist")
+    } else if !Sortable(fieldData) {
+      request.Errors = append(request.Errors, "field '" + field + "' is " + fieldData.Type + " and can not be grouped")
+    } else {
+      fields = append(fields, field)
+    }
+  }
+  if len(fields) == 0 && len(request.Errors) == 0 {
+    request.Errors = append(request.Errors, "by needs one or more fields, e.g. by=color")
+  }
+  aggregates := []Aggregate{{Function: "count"}}
+  for _, item := range strings.Split(strings.Join(queries["agg"], ","), ",") {
+    if item = strings.TrimSpace(item); item == "" {
+      continue
+    }
+    aggregate := Aggregate{Function: item}
+    if i := strings.Index(item, ":"); i != -1 {
+      aggregate = Aggregate{Function: item[:i], Field: item[i + 1:]}
+    }
+    if err := aggregate.Check(schema); err != nil {
+      request.Errors = append(request.Errors, err.Error())
+    } else {
+      aggregates = append(aggregates, aggregate)
+    }
+  }
+
+  groups := make([]*GroupRow, 0)
+  if len(request.Errors) == 0 {
+    var err error
+    if groups, err = GroupBy(schema, fields, aggregates, search.Match(request, schema)); err != nil {
+      request.Errors = append(request.Errors, err.Error())
+    }
+  }
+  offset, limit, errors := search.ParsePage(queries)
+  request.Errors = append(request.Errors, errors...)
+
+  sortKeys := make([]GroupSort, 0)
+  for _, item := range strings.Split(strings.Join(queries["sort"], ","), ",") {
+    if item = strings.TrimSpace(item); item == "" {
+      continue
+    }
+    key := GroupSort{Field: -1, Aggregate: -1, Descending: strings.HasPrefix(item, "-")}
+    item = strings.TrimPrefix(item, "-")
+    for i, field := range fields {
+      if field == item {
+        key.Field = i
+      }
+    }
+    for a, aggregate := range aggregates {
+      if aggregate.Name() == item {
+        key.Aggregate = a
+      }
+    }
+    if key.Field == -1 && key.Aggregate == -1 {
+      request.Errors = append(request.Errors, "sort '" + item + "' is not a by field or aggregate")
+      continue
+    }
+    sortKeys = append(sortKeys, key)
+  }
+  if len(sortKeys) == 0 {
+    sortKeys = append(sortKeys, GroupSort{Field: -1, Aggregate: 0, Descending: true})
+  }
+  sort.SliceStable(groups, func(i, j int) bool {
+    a, b := groups[i], groups[j]
+    for _, key := range sortKeys {
+      var x, y interface{}
+      if key.Field != -1 {
+        if a.Codes[key.Field] != -1 {
+          x = a.Codes[key.Field]
+        }
+        if b.Codes[key.Field] != -1 {
+          y = b.Codes[key.Field]
+        }
+      } else {
+        x = a.States[key.Aggregate].Value(aggregates[key.Aggregate].Function)
+        y = b.States[key.Aggregate].Value(aggregates[key.Aggregate].Function)
+      }
+      if x == nil || y == nil {
+        if (x == nil) != (y == nil) {
+          return y == nil
+        }
+        continue
+      }
+      if comparison := CompareValues(x, y); comparison != 0 {
+        return (comparison < 0) != key.Descending
+      }
+    }
+    for f := range fields {
+      if a.Codes[f] != b.Codes[f] {
+        return a.Codes[f] < b.Codes[f]
+      }
+    }
+    return false
+  })
+
+  output := map[string]interface{}{
+    "total": len(groups),
+    "offset": offset,
+    "limit": limit,
+  }
+  if len(request.Errors) > 0 {
+    output["errors"] = request.Errors
+  }
+  if offset > len(groups) {
+    offset = len(groups)
+  }
+  groups = groups[offset:]
+  if len(groups) > limit {
+    groups = groups[:limit]
+  }
+  rows := make([]map[string]interface{}, len(groups))
+  for g, group := range groups {
+    row := make(map[string]interface{}, len(fields) + len(aggregates))
+    for i, value := range group.Values(schema, fields) {
+      row[fields[i]] = value
+    }
+    for a, aggregate := range aggregates {
+      row[aggregate.Name()] = group.States[a].Value(aggregate.Function)
+    }
+    rows[g] = row
+  }
+  output["groups"] = rows
+  return output
+}
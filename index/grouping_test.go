@@ -0,0 +1,97 @@
+package index
+
+import (
+  "net/url"
+  "reflect"
+  "testing"
+)
+
+type GroupRows []map[string]interface{}
+
+func TestGroups(t *testing.T) {
+  collection := FixtureCollection()
+  tests := []struct {
+    query string
+    total int
+    want GroupRows
+  }{
+    // largest groups first, ties in the order of the by values
+    {"by=color,brand&agg=sum:price&limit=3", 12, GroupRows{
+      {"color": "blue", "brand": "globex", "count(*)": 34, "sum(price)": 731.0},
+      {"color": "green", "brand": "initech", "count(*)": 34, "sum(price)": 690.0},
+      {"color": "red", "brand": "acme", "count(*)": 34, "sum(price)": 742.0},
+    }},
+    {"by=color,brand&agg=sum:price&sort=-sum(price)&limit=2", 12, GroupRows{
+      {"color": "red", "brand": "acme", "count(*)": 34, "sum(price)": 742.0},
+      {"color": "red", "brand": "globex", "count(*)": 33, "sum(price)": 738.0},
+    }},
+    {"by=color,brand&agg=sum:price&sort=brand,-color&offset=10", 12, GroupRows{
+      {"color": "green", "brand": "umbrella", "count(*)": 33, "sum(price)": 734.0},
+      {"color": "blue", "brand": "umbrella", "count(*)": 33, "sum(price)": 692.0},
+    }},
+    {"by=color,brand&offset=20", 12, GroupRows{}},
+    {"by=color&limit=0", 3, GroupRows{}},
+    // missing values sort last either way
+    {"by=price&agg=max:popularity&sort=price&offset=49", 51, GroupRows{
+      {"price": 49.0, "count(*)": 6, "max(popularity)": 349.0},
+      {"price": nil, "count(*)": 58, "max(popularity)": 399.0},
+    }},
+    {"by=price&agg=max:popularity&sort=-price&offset=49", 51, GroupRows{
+      {"price": 0.0, "count(*)": 6, "max(popularity)": 300.0},
+      {"price": nil, "count(*)": 58, "max(popularity)": 399.0},
+    }},
+    {"by=price&agg=min:price&sort=-min(price)&offset=50", 51, GroupRows{
+      {"price": nil, "count(*)": 58, "min(price)": nil},
+    }},
+    // count of a field counts its values
+    {"by=flag&agg=count:price,min:price,max:price&sort=flag", 2, GroupRows{
+      {"flag": false, "count(*)": 200, "count(price)": 171, "min(price)": 1.0, "max(price)": 49.0},
+      {"flag": true, "count(*)": 200, "count(price)": 171, "min(price)": 0.0, "max(price)": 48.0},
+    }},
+    // only the matching records
+    {"by=brand&color=red", 4, GroupRows{
+      {"brand": "acme", "count(*)": 34},
+      {"brand": "umbrella", "count(*)": 34},
+      {"brand": "globex", "count(*)": 33},
+      {"brand": "initech", "count(*)": 33},
+    }},
+  }
+  for _, test := range tests {
+    queries, _ := url.ParseQuery(test.query)
+    output := collection.GroupBy(queries).(map[string]interface{})
+    if errors, has := output["errors"]; has {
+      t.Errorf("%s: errors %v", test.query, errors)
+      continue
+    }
+    if output["total"] != test.total {
+      t.Errorf("%s: total %v, want %d", test.query, output["total"], test.total)
+    }
+    if got := GroupRows(output["groups"].([]map[string]interface{})); !reflect.DeepEqual(got, test.want) {
+      t.Errorf("%s: groups %v, want %v", test.query, got, test.want)
+    }
+  }
+}
+
+func TestGroupsErrors(t *testing.T) {
+  collection := FixtureCollection()
+  tests := []struct {
+    query string
+    want []string
+  }{
+    {"", []string{"by needs one or more fields, e.g. by=color"}},
+    {"by=nope", []string{"field 'nope' does not exist"}},
+    {"by=color&agg=median:price", []string{"aggregate 'median' is not supported"}},
+    {"by=color&agg=sum", []string{"sum needs a field"}},
+    {"by=color&agg=sum:brand", []string{"sum needs a number field; 'brand' is string"}},
+    {"by=color&sort=brand", []string{"sort 'brand' is not a by field or aggregate"}},
+    {"by=color&sort=sum(price)", []string{"sort 'sum(price)' is not a by field or aggregate"}},
+    {"by=color&offset=-1&limit=x", []string{"offset '-1' is not a non-negative integer", "limit 'x' is not a non-negative integer"}},
+  }
+  for _, test := range tests {
+    queries, _ := url.ParseQuery(test.query)
+    output := collection.GroupBy(queries).(map[string]interface{})
+    if got, _ := output["errors"].([]string); !reflect.DeepEqual(got, test.want) {
+      t.Errorf("%q: errors %q, want %q", test.query, got, test.want)
+    }
+  }
+}
@@ -0,0 +1,86 @@
+package index
+
+import (
+  "html"
+  "strings"
+)
+
+const HIGHLIGHT_CONTEXT int = 6
+const HIGHLIGHT_FRAGMENTS int = 3
+
+// stemmed terms searched per text field
+type HighlightTerms map[string]map[string]bool
+
+func (terms HighlightTerms) Add(fields []string, clauses []TextClause) {
+  for _, field := range fields {
+    if terms[field] == nil {
+      terms[field] = make(map[string]bool)
+    }
+    for _, clause := range clauses {
+      if !clause.Exclude {
+        for _, alternative := range clause.Alternatives {
+          for _, term := range alternative {
+            terms[field][term] = true
+          }
+        }
+      }
+    }
+  }
+}
+
+// fragments of the original text around matched terms; the text, but not
+// pre and post, is html escaped when escape is set
+func (search * Search) Highlight(schema * Schema, item int, field string, terms map[string]bool, pre string, post string, escape bool) []string {
+  fieldData, has := schema.Properties[field]
+  if !has {
+    return nil
+  }
+  value, has := GenericAccessor(fieldData)(item)
+  if !has {
+    return nil
+  }
+  text := ExtractStringsFromJson(value)
+  tokens := search.TextAnalyzer(field, item).Analyze(text)
+  encode := func(str string) string { return str }
+  if escape {
+    encode = html.EscapeString
+  }
+  
+  fragments := make([]string, 0, HIGHLIGHT_FRAGMENTS)
+  for i := 0; i < len(tokens) && len(fragments) < HIGHLIGHT_FRAGMENTS; i++ {
+    if !terms[tokens[i].Text] {
+      continue
+    }
+    // extend the window while the context of further matches would overlap
+    // it, so fragments do not overlap
+    start := i - HIGHLIGHT_CONTEXT
+    if start < 0 {
+      start = 0
+    }
+    end := i + HIGHLIGHT_CONTEXT
+    for j := i + 1; j < len(tokens) && j <= end + HIGHLIGHT_CONTEXT; j++ {
+      if terms[tokens[j].Text] {
+        end = j + HIGHLIGHT_CONTEXT
+      }
+    }
+    if end >= len(tokens) {
+      end = len(tokens) - 1
+    }
+    
+    var fragment strings.Builder
+    offset := tokens[start].Start
+    for j := start; j <= end; j++ {
+      if terms[tokens[j].Text] {
+        fragment.WriteString(encode(text[offset:tokens[j].Start]))
+        fragment.WriteString(pre)
+        fragment.WriteString(encode(text[tokens[j].Start:tokens[j].End]))
+        fragment.WriteString(post)
+        offset = tokens[j].End
+      }
+    }
+    fragment.WriteString(encode(text[offset:tokens[end].End]))
+    fragments = append(fragments, fragment.String())
+    i = end
+  }
+  return fragments
+}
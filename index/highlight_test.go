@@ -0,0 +1,79 @@
+package index
+
+import (
+  "net/url"
+  "reflect"
+  "testing"
+)
+
+func HighlightCollection() Collection {
+  collection := Index(map[string][]interface{}{"body": {
+    []interface{}{"Tom & Jerry <b>chase</b> the cat"},
+    []interface{}{"cat one two three four five six seven eight nine ten eleven twelve cat thirteen fourteen fifteen sixteen seventeen eighteen nineteen twenty cat cat x y z w v u t s r q p o n m cat"},
+    []interface{}{"the cat cat", "cat dog"},
+    []interface{}{"no match here"},
+  }}, Options{FoldCase: true})
+  collection.Wait()
+  return collection
+}
+
+func TestHighlight(t *testing.T) {
+  collection := HighlightCollection()
+  terms := make(map[string]bool)
+  for _, term := range collection.search.TextAnalyzer("body", 0).LexAndStem("cat dog") {
+    terms[term] = true
+  }
+  tests := []struct {
+    item int
+    escape bool
+    want []string
+  }{
+    // context is counted in tokens, punctuation included
+    {0, true, []string{"&gt;chase&lt;/b&gt; the [cat]"}},
+    {0, false, []string{">chase</b> the [cat]"}},
+    // matches whose context would overlap share a fragment
+    {1, true, []string{
+      "[cat] one two three four five six",
+      "seven eight nine ten eleven twelve [cat] thirteen fourteen fifteen sixteen seventeen eighteen nineteen twenty [cat] [cat] x y z w v u",
+      "r q p o n m [cat]",
+    }},
+    {2, true, []string{"[cat] [cat] [cat] [dog]"}},
+    {3, true, []string{}},
+  }
+  for _, test := range tests {
+    if got := collection.search.Highlight(collection.schema, test.item, "body", terms, "[", "]", test.escape); !reflect.DeepEqual(got, test.want) {
+      t.Errorf("Highlight(%d, escape %v) = %q, want %q", test.item, test.escape, got, test.want)
+    }
+  }
+}
+
+// the markers are not escaped, the text is unless highlight_encoder=none
+func TestHighlightEncoder(t *testing.T) {
+  collection := HighlightCollection()
+  tests := []struct {
+    encoder string
+    want []string
+    errors interface{}
+  }{
+    {"", []string{"Tom &amp; <em>Jerry</em> &lt;b&gt;chase&lt;/b&gt; the <em>cat</em>"}, nil},
+    {"html", []string{"Tom &amp; <em>Jerry</em> &lt;b&gt;chase&lt;/b&gt; the <em>cat</em>"}, nil},
+    {"none", []string{"Tom & <em>Jerry</em> <b>chase</b> the <em>cat</em>"}, nil},
+    {"raw", []string{"Tom &amp; <em>Jerry</em> &lt;b&gt;chase&lt;/b&gt; the <em>cat</em>"}, []string{"highlight_encoder 'raw' is not supported"}},
+  }
+  for _, test := range tests {
+    queries := url.Values{"q": {"jerry cat"}, "highlight": {"true"}, "highlight_pre": {"<em>"}, "highlight_post": {"</em>"}}
+    if test.encoder != "" {
+      queries.Set("highlight_encoder", test.encoder)
+    }
+    output := collection.search.Search(queries, collection.schema)
+    var highlight interface{}
+    for _, result := range output["results"].([]map[string]interface{}) {
+      if result["id"] == 0 {
+        highlight = result["_highlight"]
+      }
+    }
+    if !reflect.DeepEqual(highlight, map[string][]string{"body": test.want}) || !reflect.DeepEqual(output["errors"], test.errors) {
+      t.Errorf("highlight_encoder=%s: %q, errors %v, want %q", test.encoder, highlight, output["errors"], test.want)
+    }
+  }
+}
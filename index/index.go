@@ -1,14 +1,116 @@
 package index
 
-func Index (data map[string][]interface{}) (Collection) {
+import (
+  "fmt"
+  "time"
+)
+
+type Options struct {
+  FoldCase bool
+  FoldAccents bool
+  
+  // stemming and stop words; language names or iso codes
+  Language string
+  FieldLanguages map[string]string
+  LanguageField string
+  
+  // full text score multiplier per field
+  TextWeights map[string]float64
+  
+  // default markers around highlighted terms
+  HighlightPre string
+  HighlightPost string
+  
+  // query time synonym expansion; may be nil
+  Synonyms * Synonyms
+  
+  // minimum jaccard similarity of near duplicate records
+  DuplicateThreshold float64
+  
+  // searchable fields; all when IncludeFields is empty
+  IncludeFields []string
+  ExcludeFields []string
+  
+  // fields shown in search results, in order; by entropy when empty
+  SummaryFields []string
+  
+  // fields of search results and items, see ParseProjection; empty for the
+  // summary fields and every field
+  Fields string
+  
+  // largest page size a search may ask for
+  MaxLimit int
+  
+  // searches kept for cursors, how long for and how many of their results
+  CursorCacheSize int
+  CursorTTL time.Duration
+  CursorWindow int
+}
+
+func Index (data map[string][]interface{}, options Options) (Collection) {
   schema := new(Schema)
 
   search := new(Search)
+  search.Analyzer = Analyzer{FoldCase: options.FoldCase, FoldAccents: options.FoldAccents, Language: LookupLanguage(options.Language)}
+  if search.Analyzer.Language == nil {
+    search.Analyzer.Language = LookupLanguage("english")
+  }
+  search.FieldLanguages = make(map[string]*Language)
+  for field, language := range options.FieldLanguages {
+    if fieldLanguage := LookupLanguage(language); fieldLanguage != nil {
+      search.FieldLanguages[field] = fieldLanguage
+    }
+  }
+  search.LanguageField = options.LanguageField
+  search.TextWeights = options.TextWeights
+  search.HighlightPre = options.HighlightPre
+  search.HighlightPost = options.HighlightPost
+  search.Synonyms = options.Synonyms
+  search.DuplicateThreshold = options.DuplicateThreshold
+  if search.DuplicateThreshold <= 0 || search.DuplicateThreshold > 1 {
+    search.DuplicateThreshold = 0.8
+  }
+  search.MaxLimit = options.MaxLimit
+  if search.MaxLimit <= 0 {
+    search.MaxLimit = 100
+  }
+  search.Cursors.Size = options.CursorCacheSize
+  if search.Cursors.Size <= 0 {
+    search.Cursors.Size = 256
+  }
+  search.Cursors.TTL = options.CursorTTL
+  if search.Cursors.TTL <= 0 {
+    search.Cursors.TTL = 10 * time.Minute
+  }
+  search.CursorWindow = options.CursorWindow
+  if search.CursorWindow <= 0 {
+    search.CursorWindow = 10000
+  }
+  search.IncludeFields = make(map[string]bool)
+  for _, field := range options.IncludeFields {
+    search.IncludeFields[field] = true
+  }
+  search.ExcludeFields = make(map[string]bool)
+  for _, field := range options.ExcludeFields {
+    search.ExcludeFields[field] = true
+  }
   
+  ready := make(chan bool)
   go func(){
     schema.Initialise(data);
+    if len(options.SummaryFields) > 0 {
+      schema.SetSummaryFields(options.SummaryFields)
+    }
+    if len(options.Fields) > 0 {
+      if projection, err := ParseProjection(options.Fields, schema); err == nil {
+        schema.DefaultFields = projection
+      } else {
+        fmt.Println("Default fields", err)
+      }
+    }
     search.Initialise(schema);
+    close(ready)
   }()
   
-  return Collection{schema: schema, search: search}
+  return Collection{schema: schema, search: search, ready: ready}
 }
@@ -0,0 +1,78 @@
+package index
+
+import (
+  "strings"
+  "github.com/kljensen/snowball"
+  "github.com/reiver/go-porterstemmer"
+)
+
+type Language struct {
+  Name string
+  Codes []string
+  Stem func(string) string
+  StopWords map[string]bool
+  Elisions []string
+}
+
+var Languages = []*Language{
+  &Language{Name: "none", Stem: func(word string) string { return word }},
+  &Language{Name: "english", Codes: []string{"en", "eng"}, Stem: porterstemmer.StemString},
+  &Language{Name: "german", Codes: []string{"de", "deu", "ger"}, Stem: GermanStem},
+  &Language{Name: "french", Codes: []string{"fr", "fra", "fre"}, Stem: SnowballStemmer("french"), Elisions: []string{"l", "m", "t", "qu", "n", "s", "j", "d", "c", "jusqu", "quoiqu", "lorsqu", "puisqu"}},
+  &Language{Name: "spanish", Codes: []string{"es", "spa"}, Stem: SnowballStemmer("spanish")},
+  &Language{Name: "russian", Codes: []string{"ru", "rus"}, Stem: SnowballStemmer("russian")},
+  &Language{Name: "swedish", Codes: []string{"sv", "swe"}, Stem: SnowballStemmer("swedish")},
+  &Language{Name: "norwegian", Codes: []string{"no", "nb", "nn", "nor"}, Stem: SnowballStemmer("norwegian")},
+  &Language{Name: "hungarian", Codes: []string{"hu", "hun"}, Stem: SnowballStemmer("hungarian")},
+}
+
+var languageLookup map[string]*Language
+
+func init() {
+  languageLookup = make(map[string]*Language)
+  for _, language := range Languages {
+    language.StopWords = make(map[string]bool)
+    for _, word := range strings.Fields(stopWords[language.Name]) {
+      language.StopWords[word] = true
+    }
+    languageLookup[language.Name] = language
+    for _, code := range language.Codes {
+      languageLookup[code] = language
+    }
+  }
+}
+
+// accepts names and iso codes, e.g. german, de, de-AT
+func LookupLanguage(name string) *Language {
+  name = strings.ToLower(strings.TrimSpace(name))
+  if language, has := languageLookup[name]; has {
+    return language
+  }
+  if i := strings.IndexAny(name, "-_"); i != -1 {
+    return languageLookup[name[:i]]
+  }
+  return nil
+}
+
+func SnowballStemmer(language string) func(string) string {
+  return func(word string) string {
+    if stemmed, err := snowball.Stem(word, language, true); err == nil && len(stemmed) > 0 {
+      return stemmed
+    }
+    return word
+  }
+}
+
+// l'homme -> homme
+func (language * Language) StripElision(word string) string {
+  lower := strings.ToLower(word)
+  for _, elision := range language.Elisions {
+    for _, apostrophe := range []string{"'", "’"} {
+      prefix := elision + apostrophe
+      if strings.HasPrefix(lower, prefix) && len(lower) > len(prefix) {
+        return word[len(prefix):]
+      }
+    }
+  }
+  return word
+}
@@ -0,0 +1,82 @@
+package index
+
+import (
+  "reflect"
+  "testing"
+)
+
+func TestLookupLanguage(t *testing.T) {
+  tests := []struct {
+    name string
+    want string // "" when unknown
+  }{
+    {"german", "german"},
+    {" German ", "german"},
+    {"de", "german"},
+    {"ger", "german"},
+    {"de-AT", "german"},
+    {"DE_ch", "german"},
+    {"nb", "norwegian"},
+    {"none", "none"},
+    {"xx", ""},
+    {"xx-DE", ""},
+    {"", ""},
+  }
+  for _, test := range tests {
+    got := ""
+    if language := LookupLanguage(test.name); language != nil {
+      got = language.Name
+    }
+    if got != test.want {
+      t.Errorf("LookupLanguage(%q) = %q, want %q", test.name, got, test.want)
+    }
+  }
+}
+
+func TestStripElision(t *testing.T) {
+  tests := []struct {
+    language string
+    word string
+    want string
+  }{
+    {"french", "l'homme", "homme"},
+    {"french", "L'Homme", "Homme"},
+    {"french", "l’été", "été"},
+    {"french", "jusqu'ici", "ici"},
+    {"french", "qu'il", "il"},
+    {"french", "l'", "l'"},
+    {"french", "lait", "lait"},
+    {"english", "l'homme", "l'homme"},
+  }
+  for _, test := range tests {
+    if got := LookupLanguage(test.language).StripElision(test.word); got != test.want {
+      t.Errorf("%s StripElision(%q) = %q, want %q", test.language, test.word, got, test.want)
+    }
+  }
+}
+
+// stop words are dropped in any case; the none language keeps every word
+func TestStopWords(t *testing.T) {
+  tests := []struct {
+    language string
+    text string
+    want []string
+  }{
+    {"german", "Die Häuser und der Garten", []string{"haus", "gart"}},
+    {"english", "The tree and THE garden", []string{"tree", "garden"}},
+    {"french", "L'été et le jardin", []string{"jardin"}},
+    {"none", "the house and the garden", []string{"the", "house", "and", "the", "garden"}},
+  }
+  for _, test := range tests {
+    analyzer := Analyzer{FoldCase: true, FoldAccents: true, Language: LookupLanguage(test.language)}
+    got := make([]string, 0)
+    for _, term := range analyzer.LexAndStem(test.text) {
+      if IsWord(term) {
+        got = append(got, term)
+      }
+    }
+    if !reflect.DeepEqual(got, test.want) {
+      t.Errorf("%s LexAndStem(%q) = %q, want %q", test.language, test.text, got, test.want)
+    }
+  }
+}
@@ -0,0 +1,78 @@
+package index
+
+import (
+  "fmt"
+  "math"
+  "strconv"
+  "strings"
+)
+
+// interval of the between filter; infinite bounds are open
+type NumberRange struct {
+  Low float64
+  High float64
+  LowOpen bool
+  HighOpen bool
+}
+
+func (numberRange NumberRange) Contains(value float64) bool {
+  if value < numberRange.Low || (numberRange.LowOpen && value == numberRange.Low) {
+    return false
+  }
+  if value > numberRange.High || (numberRange.HighOpen && value == numberRange.High) {
+    return false
+  }
+  return true
+}
+
+// low,high includes both bounds; interval notation such as [10,20), (10,20]
+// or (10,20) leaves bounds out. An empty bound is unbounded, e.g. [10, which
+// needs no closing bracket
+func ParseNumberRange(value string) (NumberRange, error) {
+  numberRange := NumberRange{Low: math.Inf(-1), High: math.Inf(1)}
+  text := strings.TrimSpace(value)
+  if strings.HasPrefix(text, "(") || strings.HasPrefix(text, "[") {
+    numberRange.LowOpen = text[0] == '('
+    text = text[1:]
+    if strings.HasSuffix(text, ",") {
+      text += ")"
+    }
+    if !strings.HasSuffix(text, ")") && !strings.HasSuffix(text, "]") {
+      return numberRange, fmt.Errorf("range '%s' is missing ] or )", value)
+    }
+    numberRange.HighOpen = text[len(text) - 1] == ')'
+    text = text[:len(text) - 1]
+  }
+  bounds := strings.Split(text, ",")
+  if len(bounds) != 2 {
+    return numberRange, fmt.Errorf("range '%s' needs two bounds, e.g. 10,20", value)
+  }
+  var err error
+  if bound := strings.TrimSpace(bounds[0]); bound != "" {
+    if numberRange.Low, err = strconv.ParseFloat(bound, 64); err != nil {
+      return numberRange, fmt.Errorf("range '%s' bound '%s' is not a number", value, bound)
+    }
+  }
+  if bound := strings.TrimSpace(bounds[1]); bound != "" {
+    if numberRange.High, err = strconv.ParseFloat(bound, 64); err != nil {
+      return numberRange, fmt.Errorf("range '%s' bound '%s' is not a number", value, bound)
+    }
+  }
+  if numberRange.Low > numberRange.High {
+    return numberRange, fmt.Errorf("range '%s' is empty", value)
+  }
+  return numberRange, nil
+}
+
+// comma separated numbers
+func ParseNumberList(value string) (map[float64]bool, error) {
+  values := make(map[float64]bool)
+  for _, item := range strings.Split(value, ",") {
+    number, err := strconv.ParseFloat(strings.TrimSpace(item), 64)
+    if err != nil {
+      return nil, fmt.Errorf("value '%s' is not a number", item)
+    }
+    values[number] = true
+  }
+  return values, nil
+}
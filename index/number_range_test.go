@@ -0,0 +1,115 @@
+package index
+
+import (
+  "math"
+  "net/url"
+  "reflect"
+  "testing"
+)
+
+func TestParseNumberRange(t *testing.T) {
+  inf := math.Inf(1)
+  tests := []struct {
+    value string
+    want NumberRange
+    err string
+  }{
+    {"10,20", NumberRange{Low: 10, High: 20}, ""},
+    {" 10 , 20 ", NumberRange{Low: 10, High: 20}, ""},
+    {"[10,20)", NumberRange{Low: 10, High: 20, HighOpen: true}, ""},
+    {"(10,20]", NumberRange{Low: 10, High: 20, LowOpen: true}, ""},
+    {"(10,20)", NumberRange{Low: 10, High: 20, LowOpen: true, HighOpen: true}, ""},
+    {"[-1.5,2e3]", NumberRange{Low: -1.5, High: 2000}, ""},
+    {"[10,", NumberRange{Low: 10, High: inf, HighOpen: true}, ""},
+    {"(10,", NumberRange{Low: 10, High: inf, LowOpen: true, HighOpen: true}, ""},
+    {"10,", NumberRange{Low: 10, High: inf}, ""},
+    {",20", NumberRange{Low: -inf, High: 20}, ""},
+    {"[,20)", NumberRange{Low: -inf, High: 20, HighOpen: true}, ""},
+    {"20,20", NumberRange{Low: 20, High: 20}, ""},
+    {"20,10", NumberRange{}, "range '20,10' is empty"},
+    {"[10,20", NumberRange{}, "range '[10,20' is missing ] or )"},
+    {"(10", NumberRange{}, "range '(10' is missing ] or )"},
+    {"10", NumberRange{}, "range '10' needs two bounds, e.g. 10,20"},
+    {"1,2,3", NumberRange{}, "range '1,2,3' needs two bounds, e.g. 10,20"},
+    {"a,20", NumberRange{}, "range 'a,20' bound 'a' is not a number"},
+    {"[10,b)", NumberRange{}, "range '[10,b)' bound 'b' is not a number"},
+  }
+  for _, test := range tests {
+    got, err := ParseNumberRange(test.value)
+    if test.err != "" {
+      if err == nil || err.Error() != test.err {
+        t.Errorf("ParseNumberRange(%q) error = %v, want %q", test.value, err, test.err)
+      }
+    } else if err != nil || !reflect.DeepEqual(got, test.want) {
+      t.Errorf("ParseNumberRange(%q) = %+v, %v, want %+v", test.value, got, err, test.want)
+    }
+  }
+}
+
+func TestNumberRangeContains(t *testing.T) {
+  numberRange := NumberRange{Low: 10, High: 20, HighOpen: true}
+  for value, want := range map[float64]bool{9.99: false, 10: true, 15: true, 19.99: true, 20: false} {
+    if got := numberRange.Contains(value); got != want {
+      t.Errorf("[10,20) contains %v = %v, want %v", value, got, want)
+    }
+  }
+  numberRange = NumberRange{Low: 10, High: math.Inf(1), LowOpen: true, HighOpen: true}
+  for value, want := range map[float64]bool{10: false, 10.01: true, 1e300: true} {
+    if got := numberRange.Contains(value); got != want {
+      t.Errorf("(10, contains %v = %v, want %v", value, got, want)
+    }
+  }
+}
+
+func TestParseNumberList(t *testing.T) {
+  tests := []struct {
+    value string
+    want map[float64]bool
+    err string
+  }{
+    {"1,2,3", map[float64]bool{1: true, 2: true, 3: true}, ""},
+    {" 1.5 , -2 ", map[float64]bool{1.5: true, -2: true}, ""},
+    {"4", map[float64]bool{4: true}, ""},
+    {"1,x,3", nil, "value 'x' is not a number"},
+    {"1,,3", nil, "value '' is not a number"},
+  }
+  for _, test := range tests {
+    got, err := ParseNumberList(test.value)
+    if test.err != "" {
+      if err == nil || err.Error() != test.err {
+        t.Errorf("ParseNumberList(%q) error = %v, want %q", test.value, err, test.err)
+      }
+    } else if err != nil || !reflect.DeepEqual(got, test.want) {
+      t.Errorf("ParseNumberList(%q) = %v, %v, want %v", test.value, got, err, test.want)
+    }
+  }
+}
+
+func TestNumberRangeFilters(t *testing.T) {
+  collection := FixtureCollection()
+  tests := []struct {
+    value string
+    total int
+    err string
+  }{
+    {"between:10,20", 76, ""},
+    {"between:[10,20)", 69, ""},
+    {"between:(10,20]", 69, ""},
+    {"between:[10,", 274, ""},
+    {"between:,5", 41, ""},
+    {"between:20,10", 400, "field 'price' range '20,10' is empty"},
+    {"between:[10,20", 400, "field 'price' range '[10,20' is missing ] or )"},
+    {"in:1,2,49", 20, ""},
+    {"in:1,two", 400, "field 'price' value 'two' is not a number"},
+  }
+  for _, test := range tests {
+    output := collection.search.Search(url.Values{"price": {test.value}}, collection.schema)
+    err := ""
+    if errors, has := output["errors"]; has {
+      err = errors.([]string)[0]
+    }
+    if output["total"] != test.total || err != test.err {
+      t.Errorf("price=%s: total = %v, error %q, want %d, %q", test.value, output["total"], err, test.total, test.err)
+    }
+  }
+}
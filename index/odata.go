@@ -0,0 +1,363 @@
+package index
+
+import (
+  "bytes"
+  "encoding/xml"
+  "fmt"
+  "net/url"
+  "sort"
+  "strconv"
+  "strings"
+)
+
+// OData v4 feed of the collection as one entity set, Items, keyed by id
+
+var ODATA_COMPARISONS = map[string]string{"eq": "=", "ne": "!=", "lt": "<", "le": "<=", "gt": ">", "ge": ">="}
+
+var ODATA_FUNCTIONS = map[string]bool{"contains": true, "startswith": true, "endswith": true}
+
+var ODATA_OPTIONS = map[string]bool{"$filter": true, "$select": true, "$orderby": true, "$top": true, "$skip": true, "$count": true, "$format": true}
+
+// $filter uses the sql tokenizer; and, or, not, null, true and false come
+// through as keywords, operators and functions as identifiers
+type ODataParser struct {
+  * SQLParser
+}
+
+func (search * Search) ParseODataFilter(filter string, schema * Schema) (* QueryNode, error) {
+  tokens, err := TokenizeSQL(filter)
+  if err != nil {
+    return nil, err
+  }
+  parser := ODataParser{&SQLParser{Statement: filter, Tokens: tokens, Search: search, Schema: schema}}
+  node, err := parser.ParseOr()
+  if err == nil && parser.Peek().Kind != "end" {
+    err = parser.Unexpected()
+  }
+  return node, err
+}
+
+func (parser ODataParser) ParseOr() (* QueryNode, error) {
+  node, err := parser.ParseAnd()
+  if err != nil {
+    return nil, err
+  }
+  children := []*QueryNode{node}
+  for parser.Accept("OR") {
+    if node, err = parser.ParseAnd(); err != nil {
+      return nil, err
+    }
+    children = append(children, node)
+  }
+  if len(children) == 1 {
+    return node, nil
+  }
+  return &QueryNode{Operator: "or", Children: children}, nil
+}
+
+func (parser ODataParser) ParseAnd() (* QueryNode, error) {
+  node, err := parser.ParseNot()
+  if err != nil {
+    return nil, err
+  }
+  children := []*QueryNode{node}
+  for parser.Accept("AND") {
+    if node, err = parser.ParseNot(); err != nil {
+      return nil, err
+    }
+    children = append(children, node)
+  }
+  if len(children) == 1 {
+    return node, nil
+  }
+  return &QueryNode{Operator: "and", Children: children}, nil
+}
+
+func (parser ODataParser) ParseNot() (* QueryNode, error) {
+  if parser.Accept("NOT") {
+    node, err := parser.ParseNot()
+    if err != nil {
+      return nil, err
+    }
+    return &QueryNode{Operator: "not", Children: []*QueryNode{node}}, nil
+  }
+  if parser.Accept("(") {
+    node, err := parser.ParseOr()
+    if err != nil {
+      return nil, err
+    }
+    return node, parser.Expect(")")
+  }
+  return parser.ParseCondition()
+}
+
+// field names may collide with sql keywords; they keep the original text
+func (parser ODataParser) Field() (string, error) {
+  token := parser.Peek()
+  if token.Kind == "keyword" {
+    text := parser.Statement[token.Position:token.Position + len(token.Text)]
+    if _, has := parser.Schema.Properties[text]; has {
+      parser.Next++
+      return text, nil
+    }
+  }
+  return parser.SQLParser.Field()
+}
+
+// field op value, field op null, or function(field, 'text') [eq true|false]
+func (parser ODataParser) ParseCondition() (* QueryNode, error) {
+  start := parser.Peek()
+  function := strings.ToLower(start.Text)
+  if start.Kind == "identifier" && ODATA_FUNCTIONS[function] && parser.Tokens[parser.Next + 1].Text == "(" {
+    parser.Next += 2
+    field, err := parser.Field()
+    if err != nil {
+      return nil, err
+    }
+    if err := parser.Expect(","); err != nil {
+      return nil, err
+    }
+    text, err := parser.Literal()
+    if err != nil {
+      return nil, err
+    }
+    if err := parser.Expect(")"); err != nil {
+      return nil, err
+    }
+    negate := false
+    if token := parser.Peek(); token.Kind == "identifier" && (token.Text == "eq" || token.Text == "ne") {
+      parser.Next++
+      value, err := parser.Literal()
+      if _, isBool := value.(bool); err != nil || !isBool {
+        return nil, parser.Error("expected true or false")
+      }
+      negate = value.(bool) != (token.Text == "eq")
+    }
+    node, err := ComparisonNode(parser.Schema, field, function, []interface{}{text}, negate)
+    if err != nil {
+      return nil, SQLError(err.Error(), parser.Statement, start.Position)
+    }
+    return node, nil
+  }
+
+  field, err := parser.Field()
+  if err != nil {
+    return nil, err
+  }
+  token := parser.Peek()
+  comparison, has := ODATA_COMPARISONS[token.Text]
+  if token.Kind != "identifier" || !has {
+    return nil, parser.Error("expected eq, ne, lt, le, gt or ge")
+  }
+  parser.Next++
+  var node * QueryNode
+  if parser.Accept("NULL") {
+    if token.Text != "eq" && token.Text != "ne" {
+      return nil, SQLError("null can only be compared with eq or ne", parser.Statement, token.Position)
+    }
+    node, err = ComparisonNode(parser.Schema, field, "null", nil, token.Text == "ne")
+  } else {
+    var value interface{}
+    if value, err = parser.Literal(); err != nil {
+      return nil, err
+    }
+    node, err = ComparisonNode(parser.Schema, field, comparison, []interface{}{value}, false)
+    // ne also matches missing values since null is not equal to anything
+    if err == nil && token.Text == "ne" {
+      node, err = ComparisonNode(parser.Schema, field, "=", []interface{}{value}, false)
+      node = &QueryNode{Operator: "not", Children: []*QueryNode{node}}
+    }
+  }
+  if err != nil {
+    return nil, SQLError(err.Error(), parser.Statement, start.Position)
+  }
+  return node, nil
+}
+
+// "price desc,name" into sql order keys
+func ODataOrderBy(orderBy string, schema * Schema) ([]SQLOrder, error) {
+  orders := make([]SQLOrder, 0)
+  for _, key := range strings.Split(orderBy, ",") {
+    words := strings.Fields(key)
+    if len(words) == 0 || len(words) > 2 {
+      return nil, fmt.Errorf("invalid key '%s'", strings.TrimSpace(key))
+    }
+    if _, has := schema.Properties[words[0]]; !has {
+      return nil, fmt.Errorf("unknown field '%s'", words[0])
+    }
+    order := SQLOrder{Column: SQLColumn{Name: words[0], Field: words[0]}}
+    if len(words) == 2 {
+      switch strings.ToLower(words[1]) {
+        case "asc":
+          break;
+        case "desc":
+          order.Descending = true
+          break;
+        default:
+          return nil, fmt.Errorf("'%s' must be asc or desc", words[1])
+      }
+    }
+    orders = append(orders, order)
+  }
+  return orders, nil
+}
+
+// query options of GET Items become an sql query
+func (search * Search) ODataQuery(queries url.Values, schema * Schema) (* SQLQuery, error) {
+  query := &SQLQuery{From: "Items", Star: true, Limit: -1}
+  options := make([]string, 0, len(queries))
+  for option, _ := range queries {
+    options = append(options, option)
+  }
+  sort.Strings(options)
+  for _, option := range options {
+    if strings.HasPrefix(option, "$") && !ODATA_OPTIONS[option] {
+      return nil, fmt.Errorf("%s is not supported", option)
+    }
+  }
+
+  var err error
+  if filter := queries.Get("$filter"); filter != "" {
+    if query.Where, err = search.ParseODataFilter(filter, schema); err != nil {
+      return nil, fmt.Errorf("$filter: %s", err.Error())
+    }
+  }
+  if selected := queries.Get("$select"); selected != "" && selected != "*" {
+    query.Star = false
+    for _, field := range strings.Split(selected, ",") {
+      field = strings.TrimSpace(field)
+      if _, has := schema.Properties[field]; !has {
+        return nil, fmt.Errorf("$select: unknown field '%s'", field)
+      }
+      query.Columns = append(query.Columns, SQLColumn{Name: field, Field: field})
+    }
+  }
+  if orderBy := queries.Get("$orderby"); orderBy != "" {
+    if query.OrderBy, err = ODataOrderBy(orderBy, schema); err != nil {
+      return nil, fmt.Errorf("$orderby: %s", err.Error())
+    }
+  }
+  if top := queries.Get("$top"); top != "" {
+    if query.Limit, err = strconv.Atoi(top); err != nil || query.Limit < 0 {
+      return nil, fmt.Errorf("$top: expected a whole number")
+    }
+  }
+  if skip := queries.Get("$skip"); skip != "" {
+    if query.Offset, err = strconv.Atoi(skip); err != nil || query.Offset < 0 {
+      return nil, fmt.Errorf("$skip: expected a whole number")
+    }
+  }
+  if count := queries.Get("$count"); count != "" && count != "true" && count != "false" {
+    return nil, fmt.Errorf("$count: expected true or false")
+  }
+  return query, nil
+}
+
+// GET Items; errors use the odata error shape
+func (search * Search) OData(queries url.Values, schema * Schema) map[string]interface{} {
+  query, err := search.ODataQuery(queries, schema)
+  var result * SQLResult
+  if err == nil {
+    result, err = search.RunSQL(query, schema)
+  }
+  if err != nil {
+    return map[string]interface{}{"error": map[string]interface{}{"code": "BadRequest", "message": err.Error()}}
+  }
+
+  // missing values are left out unless selected
+  items := make([]map[string]interface{}, len(result.Rows))
+  for i, row := range result.Rows {
+    item := make(map[string]interface{}, len(row) + 1)
+    for c, column := range query.Columns {
+      if row[c] != nil || !query.Star {
+        item[column.Name] = row[c]
+      }
+    }
+    item["id"] = result.Items[i]
+    items[i] = item
+  }
+  output := map[string]interface{}{"@odata.context": "$metadata#Items", "value": items}
+  if queries.Get("$count") == "true" {
+    output["@odata.count"] = result.Total
+  }
+  return output
+}
+
+// service document listing the entity set
+func ODataService() map[string]interface{} {
+  return map[string]interface{}{
+    "@odata.context": "$metadata",
+    "value": []map[string]interface{}{{"name": "Items", "kind": "EntitySet", "url": "Items"}},
+  }
+}
+
+type EdmxProperty struct {
+  Name string `xml:"Name,attr"`
+  Type string `xml:"Type,attr"`
+  Nullable string `xml:"Nullable,attr,omitempty"`
+}
+
+type EdmxPropertyRef struct {
+  Name string `xml:"Name,attr"`
+}
+
+type EdmxEntityType struct {
+  Name string `xml:"Name,attr"`
+  OpenType bool `xml:"OpenType,attr"`
+  Key []EdmxPropertyRef `xml:"Key>PropertyRef"`
+  Properties []EdmxProperty `xml:"Property"`
+}
+
+type EdmxEntitySet struct {
+  Name string `xml:"Name,attr"`
+  EntityType string `xml:"EntityType,attr"`
+}
+
+type EdmxSchema struct {
+  Xmlns string `xml:"xmlns,attr"`
+  Namespace string `xml:"Namespace,attr"`
+  EntityTypes []EdmxEntityType `xml:"EntityType"`
+  EntityContainer struct {
+    Name string `xml:"Name,attr"`
+    EntitySets []EdmxEntitySet `xml:"EntitySet"`
+  } `xml:"EntityContainer"`
+}
+
+type Edmx struct {
+  XMLName xml.Name `xml:"edmx:Edmx"`
+  Xmlns string `xml:"xmlns:edmx,attr"`
+  Version string `xml:"Version,attr"`
+  Schemas []EdmxSchema `xml:"edmx:DataServices>Schema"`
+}
+
+var EDM_TYPES = map[string]string{"number": "Edm.Double", "string": "Edm.String", "boolean": "Edm.Boolean", "vector": "Collection(Edm.Double)"}
+
+// $metadata; arrays and objects are left to the open type
+func ODataMetadata(schema * Schema) []byte {
+  entityType := EdmxEntityType{Name: "Item", OpenType: true, Key: []EdmxPropertyRef{{"id"}}}
+  entityType.Properties = append(entityType.Properties, EdmxProperty{"id", "Edm.Int32", "false"})
+  fields := make([]string, 0, len(schema.Properties))
+  for field, _ := range schema.Properties {
+    fields = append(fields, field)
+  }
+  sort.Strings(fields)
+  for _, field := range fields {
+    if edmType, has := EDM_TYPES[schema.Properties[field].Type]; has && field != "id" {
+      entityType.Properties = append(entityType.Properties, EdmxProperty{Name: field, Type: edmType})
+    }
+  }
+
+  edmxSchema := EdmxSchema{Xmlns: "http://docs.oasis-open.org/odata/ns/edm", Namespace: "RestApi", EntityTypes: []EdmxEntityType{entityType}}
+  edmxSchema.EntityContainer.Name = "Container"
+  edmxSchema.EntityContainer.EntitySets = []EdmxEntitySet{{"Items", "RestApi.Item"}}
+  document := Edmx{Xmlns: "http://docs.oasis-open.org/odata/ns/edmx", Version: "4.0", Schemas: []EdmxSchema{edmxSchema}}
+
+  var buffer bytes.Buffer
+  buffer.WriteString(xml.Header)
+  encoder := xml.NewEncoder(&buffer)
+  encoder.Indent("", "  ")
+  if err := encoder.Encode(document); err != nil {
+    fmt.Println("XML ENCODING ERROR", err)
+  }
+  return buffer.Bytes()
+}
@@ -0,0 +1,146 @@
+package index
+
+import (
+  "encoding/xml"
+  "net/url"
+  "reflect"
+  "testing"
+)
+
+func ODataIds(output map[string]interface{}) []int {
+  ids := make([]int, 0)
+  items, _ := output["value"].([]map[string]interface{})
+  for _, item := range items {
+    ids = append(ids, item["id"].(int))
+  }
+  return ids
+}
+
+func TestODataFilter(t *testing.T) {
+  collection := FixtureCollection()
+  tests := []struct {
+    filter string
+    count int
+  }{
+    {"price lt 5 and color eq 'red'", 12},
+    {"color eq 'red' or color eq 'blue'", 267},
+    {"not (color eq 'red' or color eq 'blue')", 133},
+    {"price ge 45 or popularity lt 2", 36},
+    {"price eq null", 58},
+    {"price ne null", 342},
+    // ne and not match missing values
+    {"price ne 3", 393},
+    {"not price lt 10", 332},
+    {"contains(title, 'Television')", 134},
+    {"contains(title, 'Television') eq false", 266},
+    {"contains(title, 'Television') ne true and startswith(brand, 'ac')", 66},
+    {"startswith(brand, 'ac') and endswith(title, 'remote')", 34},
+    {"flag eq true", 200},
+  }
+  for _, test := range tests {
+    output := collection.search.OData(url.Values{"$filter": {test.filter}, "$count": {"true"}, "$top": {"0"}}, collection.schema)
+    if output["@odata.count"] != test.count {
+      t.Errorf("$filter=%s count = %v, want %d", test.filter, output["@odata.count"], test.count)
+    }
+  }
+
+  output := collection.search.OData(url.Values{"$filter": {"price lt 5 and color eq 'red'"}, "$orderby": {"popularity"}}, collection.schema)
+  if ids, want := ODataIds(output), []int{3, 51, 54, 102, 150, 153, 201, 204, 300, 303, 351, 354}; !reflect.DeepEqual(ids, want) {
+    t.Errorf("ids = %v, want %v", ids, want)
+  }
+}
+
+func TestODataOptions(t *testing.T) {
+  collection := FixtureCollection()
+  tests := []struct {
+    query string
+    ids []int
+    count interface{}
+  }{
+    {"$orderby=price desc,popularity&$top=3", []int{99, 149, 199}, nil},
+    // missing values sort first ascending and last descending
+    {"$orderby=price,popularity&$top=2", []int{0, 7}, nil},
+    {"$orderby=price desc,popularity&$skip=342&$top=3", []int{0, 7, 14}, nil},
+    {"$filter=color eq 'red'&$orderby=popularity&$skip=2&$top=2&$count=true", []int{6, 9}, 134},
+    {"$filter=color eq 'red'&$orderby=popularity&$skip=200&$count=true", []int{}, 134},
+    {"$orderby=popularity desc&$top=2&$count=false", []int{399, 398}, nil},
+  }
+  for _, test := range tests {
+    queries, _ := url.ParseQuery(test.query)
+    output := collection.search.OData(queries, collection.schema)
+    if ids := ODataIds(output); !reflect.DeepEqual(ids, test.ids) || output["@odata.count"] != test.count {
+      t.Errorf("%s = %v, count %v, want %v, count %v", test.query, ids, output["@odata.count"], test.ids, test.count)
+    }
+  }
+
+  // missing values are left out unless selected
+  output := collection.search.OData(url.Values{"$orderby": {"popularity"}, "$top": {"1"}}, collection.schema)
+  if item := output["value"].([]map[string]interface{})[0]; len(item) != 6 || item["popularity"] != 0.0 {
+    t.Errorf("item 0 = %v", item)
+  }
+  output = collection.search.OData(url.Values{"$select": {"price, flag"}, "$orderby": {"popularity"}, "$top": {"1"}}, collection.schema)
+  if item, want := output["value"].([]map[string]interface{})[0], map[string]interface{}{"id": 0, "price": nil, "flag": true}; !reflect.DeepEqual(item, want) {
+    t.Errorf("item 0 = %v, want %v", item, want)
+  }
+}
+
+func TestODataErrors(t *testing.T) {
+  collection := FixtureCollection()
+  tests := []struct {
+    query string
+    message string
+  }{
+    {"$filter=price eq", "$filter: expected a value at character 9"},
+    {"$filter=nope eq 1", "$filter: unknown field 'nope' at character 1"},
+    {"$filter=price lt null", "$filter: null can only be compared with eq or ne at character 7"},
+    {"$filter=price like 1", "$filter: expected eq, ne, lt, le, gt or ge at character 7"},
+    {"$filter=contains(title, 'x') eq 1", "$filter: expected true or false at character 26"},
+    {"$filter=price eq 1 color", "$filter: unexpected 'color' at character 12"},
+    {"$orderby=price up", "$orderby: 'up' must be asc or desc"},
+    {"$orderby=nope", "$orderby: unknown field 'nope'"},
+    {"$select=nope", "$select: unknown field 'nope'"},
+    {"$top=-1", "$top: expected a whole number"},
+    {"$skip=x", "$skip: expected a whole number"},
+    {"$count=maybe", "$count: expected true or false"},
+    {"$expand=x", "$expand is not supported"},
+  }
+  for _, test := range tests {
+    queries, _ := url.ParseQuery(test.query)
+    output := collection.search.OData(queries, collection.schema)
+    want := map[string]interface{}{"error": map[string]interface{}{"code": "BadRequest", "message": test.message}}
+    if !reflect.DeepEqual(output, want) {
+      t.Errorf("%s = %v, want %v", test.query, output, want)
+    }
+  }
+}
+
+func TestODataMetadata(t *testing.T) {
+  var document struct {
+    Version string `xml:"Version,attr"`
+    Schema struct {
+      Namespace string `xml:"Namespace,attr"`
+      EntityType EdmxEntityType
+      EntityContainer struct {
+        EntitySets []EdmxEntitySet `xml:"EntitySet"`
+      }
+    } `xml:"DataServices>Schema"`
+  }
+  if err := xml.Unmarshal(ODataMetadata(FixtureCollection().schema), &document); err != nil {
+    t.Fatal(err)
+  }
+  entityType := EdmxEntityType{Name: "Item", OpenType: true, Key: []EdmxPropertyRef{{"id"}}, Properties: []EdmxProperty{
+    {"id", "Edm.Int32", "false"},
+    {"brand", "Edm.String", ""},
+    {"color", "Edm.String", ""},
+    {"flag", "Edm.Boolean", ""},
+    {"popularity", "Edm.Double", ""},
+    {"price", "Edm.Double", ""},
+    {"title", "Edm.String", ""},
+  }}
+  if document.Version != "4.0" || document.Schema.Namespace != "RestApi" || !reflect.DeepEqual(document.Schema.EntityType, entityType) {
+    t.Errorf("metadata = %+v", document)
+  }
+  if sets := document.Schema.EntityContainer.EntitySets; !reflect.DeepEqual(sets, []EdmxEntitySet{{"Items", "RestApi.Item"}}) {
+    t.Errorf("entity sets = %+v", sets)
+  }
+}
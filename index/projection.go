@@ -0,0 +1,197 @@
+package index
+
+import (
+  "fmt"
+  "net/url"
+  "strings"
+)
+
+// fields=title,author.name,-body
+// * is every field, - leaves a field out and dotted paths select inside
+// object fields (and each object of an array). Only exclusions start from
+// the default fields.
+type Projection struct {
+  All bool
+  Include [][]string
+  Exclude [][]string
+}
+
+func ParseProjection(value string, schema * Schema) (* Projection, error) {
+  projection := new(Projection)
+  for _, item := range strings.Split(value, ",") {
+    item = strings.TrimSpace(item)
+    if item == "" {
+      continue
+    }
+    exclude := strings.HasPrefix(item, "-")
+    if exclude {
+      item = item[1:]
+    }
+    if item == "*" && !exclude {
+      projection.All = true
+      continue
+    }
+    path := strings.Split(item, ".")
+    for _, part := range path {
+      if part == "" || part == "*" {
+        return nil, fmt.Errorf("field '%s' is not a field or dotted path", item)
+      }
+    }
+    if _, has := schema.Properties[path[0]]; !has {
+      return nil, fmt.Errorf("field '%s' does not exist", path[0])
+    }
+    if exclude {
+      projection.Exclude = append(projection.Exclude, path)
+    } else {
+      projection.Include = append(projection.Include, path)
+    }
+  }
+  return projection, nil
+}
+
+// nil projection means the default fields; summary picks the summary fields
+// over every field when there is no configured default
+func (schema * Schema) Project(index int, projection * Projection, summary bool) map[string]interface{} {
+  var output map[string]interface{}
+  if projection != nil && (projection.All || len(projection.Include) > 0) {
+    output = projection.Select(schema, index)
+  } else if schema.DefaultFields != nil && projection != schema.DefaultFields {
+    output = schema.Project(index, schema.DefaultFields, summary)
+  } else if summary {
+    output = schema.GetSummary(index, 9)
+  } else {
+    output = schema.GetItem(index)
+  }
+  if projection != nil {
+    for _, path := range projection.Exclude {
+      if len(path) == 1 {
+        delete(output, path[0])
+      } else if value, has := output[path[0]]; has {
+        output[path[0]] = ExcludePath(value, path[1:])
+      }
+    }
+  }
+  return output
+}
+
+func (projection * Projection) Select(schema * Schema, index int) map[string]interface{} {
+  if projection.All {
+    return schema.GetItem(index)
+  }
+  output := make(map[string]interface{}, len(projection.Include))
+  for _, path := range projection.Include {
+    fieldData := schema.Properties[path[0]]
+    valueIndex := fieldData.ValueIndex[index]
+    if valueIndex == -1 {
+      continue
+    }
+    value, found := fieldData.UniqueValues[valueIndex], true
+    if len(path) > 1 {
+      value, found = SelectPath(value, path[1:])
+    }
+    if found {
+      output[path[0]] = MergeValues(output[path[0]], value)
+    }
+  }
+  return output
+}
+
+// the part of value on path, keeping the objects around it
+func SelectPath(value interface{}, path []string) (interface{}, bool) {
+  switch value := value.(type) {
+    case map[string]interface{}:
+      child, has := value[path[0]]
+      if !has {
+        return nil, false
+      }
+      if len(path) > 1 {
+        if child, has = SelectPath(child, path[1:]); !has {
+          return nil, false
+        }
+      }
+      return map[string]interface{}{path[0]: child}, true
+    case []interface{}:
+      // every object stays so paths into the same array line up
+      output := make([]interface{}, 0, len(value))
+      for _, element := range value {
+        if _, isObject := element.(map[string]interface{}); !isObject {
+          continue
+        }
+        if child, has := SelectPath(element, path); has {
+          output = append(output, child)
+        } else {
+          output = append(output, map[string]interface{}{})
+        }
+      }
+      return output, len(output) > 0
+  }
+  return nil, false
+}
+
+// joins two selections of the same value into a copy
+func MergeValues(a interface{}, b interface{}) interface{} {
+  switch x := a.(type) {
+    case map[string]interface{}:
+      if y, isObject := b.(map[string]interface{}); isObject {
+        output := make(map[string]interface{}, len(x) + len(y))
+        for key, value := range x {
+          output[key] = value
+        }
+        for key, value := range y {
+          output[key] = MergeValues(output[key], value)
+        }
+        return output
+      }
+      break;
+    case []interface{}:
+      if y, isArray := b.([]interface{}); isArray && len(x) == len(y) {
+        output := make([]interface{}, len(x))
+        for i := range x {
+          output[i] = MergeValues(x[i], y[i])
+        }
+        return output
+      }
+      break;
+  }
+  return b
+}
+
+// a copy of value without path; stored values are shared so never modified
+func ExcludePath(value interface{}, path []string) interface{} {
+  switch value := value.(type) {
+    case map[string]interface{}:
+      child, has := value[path[0]]
+      if !has {
+        return value
+      }
+      output := make(map[string]interface{}, len(value))
+      for key, other := range value {
+        output[key] = other
+      }
+      if len(path) == 1 {
+        delete(output, path[0])
+      } else {
+        output[path[0]] = ExcludePath(child, path[1:])
+      }
+      return output
+    case []interface{}:
+      output := make([]interface{}, len(value))
+      for i, element := range value {
+        output[i] = ExcludePath(element, path)
+      }
+      return output
+  }
+  return value
+}
+
+// fields=... of an item fetch; errors replace the item
+func (schema * Schema) GetProjectedItem(index int, queries url.Values) map[string]interface{} {
+  var projection * Projection
+  if fields, has := queries["fields"]; has {
+    var err error
+    if projection, err = ParseProjection(strings.Join(fields, ","), schema); err != nil {
+      return map[string]interface{}{"errors": []string{err.Error()}}
+    }
+  }
+  return schema.Project(index, projection, false)
+}
@@ -0,0 +1,102 @@
+package index
+
+import (
+  "encoding/json"
+  "reflect"
+  "testing"
+)
+
+func TestParseProjection(t *testing.T) {
+  schema := FixtureCollection().schema
+  tests := []struct {
+    value string
+    want * Projection
+    err string
+  }{
+    {"title,price", &Projection{Include: [][]string{{"title"}, {"price"}}}, ""},
+    {"*,-title", &Projection{All: true, Exclude: [][]string{{"title"}}}, ""},
+    {"-title", &Projection{Exclude: [][]string{{"title"}}}, ""},
+    {"title.name.first", &Projection{Include: [][]string{{"title", "name", "first"}}}, ""},
+    {"", &Projection{}, ""},
+    {"nope", nil, "field 'nope' does not exist"},
+    {"title.", nil, "field 'title.' is not a field or dotted path"},
+    {"title.*", nil, "field 'title.*' is not a field or dotted path"},
+    {"-*", nil, "field '*' is not a field or dotted path"},
+  }
+  for _, test := range tests {
+    projection, err := ParseProjection(test.value, schema)
+    if err != nil {
+      if err.Error() != test.err {
+        t.Errorf("ParseProjection(%q) error = %v, want %q", test.value, err, test.err)
+      }
+    } else if test.err != "" || !reflect.DeepEqual(projection, test.want) {
+      t.Errorf("ParseProjection(%q) = %+v, want %+v, error %q", test.value, projection, test.want, test.err)
+    }
+  }
+}
+
+func TestProjectionPaths(t *testing.T) {
+  value := map[string]interface{}{
+    "name": "Ann",
+    "address": map[string]interface{}{"city": "Oslo", "zip": "0150"},
+    "books": []interface{}{
+      map[string]interface{}{"title": "A", "year": 2001.0},
+      map[string]interface{}{"year": 2005.0},
+      "loose",
+    },
+  }
+  tests := []struct {
+    path []string
+    selected string
+    excluded string
+  }{
+    {[]string{"name"}, `{"name":"Ann"}`, `{"address":{"city":"Oslo","zip":"0150"},"books":[{"title":"A","year":2001},{"year":2005},"loose"]}`},
+    {[]string{"address", "city"}, `{"address":{"city":"Oslo"}}`, `{"address":{"zip":"0150"},"books":[{"title":"A","year":2001},{"year":2005},"loose"],"name":"Ann"}`},
+    // every object of an array stays so paths into it line up
+    {[]string{"books", "title"}, `{"books":[{"title":"A"},{}]}`, `{"address":{"city":"Oslo","zip":"0150"},"books":[{"year":2001},{"year":2005},"loose"],"name":"Ann"}`},
+    {[]string{"nope"}, `null`, `{"address":{"city":"Oslo","zip":"0150"},"books":[{"title":"A","year":2001},{"year":2005},"loose"],"name":"Ann"}`},
+    {[]string{"name", "first"}, `null`, `{"address":{"city":"Oslo","zip":"0150"},"books":[{"title":"A","year":2001},{"year":2005},"loose"],"name":"Ann"}`},
+  }
+  for _, test := range tests {
+    selected, _ := SelectPath(value, test.path)
+    encoded, _ := json.Marshal(selected)
+    if string(encoded) != test.selected {
+      t.Errorf("SelectPath(%v) = %s, want %s", test.path, encoded, test.selected)
+    }
+    encoded, _ = json.Marshal(ExcludePath(value, test.path))
+    if string(encoded) != test.excluded {
+      t.Errorf("ExcludePath(%v) = %s, want %s", test.path, encoded, test.excluded)
+    }
+  }
+  // stored values are shared, so neither may change them
+  encoded, _ := json.Marshal(value)
+  if want := `{"address":{"city":"Oslo","zip":"0150"},"books":[{"title":"A","year":2001},{"year":2005},"loose"],"name":"Ann"}`; string(encoded) != want {
+    t.Errorf("value changed to %s", encoded)
+  }
+}
+
+func TestMergeValues(t *testing.T) {
+  tests := []struct {
+    a string
+    b string
+    want string
+  }{
+    {`{"address":{"city":"Oslo"}}`, `{"address":{"zip":"0150"}}`, `{"address":{"city":"Oslo","zip":"0150"}}`},
+    {`[{"title":"A"},{}]`, `[{"year":2001},{"year":2005}]`, `[{"title":"A","year":2001},{"year":2005}]`},
+    {`[{"title":"A"}]`, `[{"year":2001},{"year":2005}]`, `[{"year":2001},{"year":2005}]`},
+    {`null`, `{"city":"Oslo"}`, `{"city":"Oslo"}`},
+    {`"x"`, `"y"`, `"y"`},
+  }
+  for _, test := range tests {
+    var a, b interface{}
+    json.Unmarshal([]byte(test.a), &a)
+    json.Unmarshal([]byte(test.b), &b)
+    encoded, _ := json.Marshal(MergeValues(a, b))
+    if string(encoded) != test.want {
+      t.Errorf("MergeValues(%s, %s) = %s, want %s", test.a, test.b, encoded, test.want)
+    }
+    if original, _ := json.Marshal(a); string(original) != test.a {
+      t.Errorf("MergeValues changed %s to %s", test.a, original)
+    }
+  }
+}
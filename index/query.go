@@ -0,0 +1,349 @@
+package index
+
+import (
+  "fmt"
+  "math"
+  "sort"
+  "strings"
+  "unicode"
+  "unicode/utf8"
+)
+
+// boolean query tree from the q parameter, e.g.
+// (color:red OR color:blue) AND price:lessThan:100 AND NOT brand:acme
+type QueryNode struct {
+  Operator string `json:"operator,omitempty"` // and, or, not, optional; empty for filters
+  Children []*QueryNode `json:"children,omitempty"`
+  Field string `json:"field,omitempty"`
+  Filter string `json:"filter,omitempty"`
+  Value string `json:"value,omitempty"`
+  // replaces the filter when set
+  Predicate func(int) bool `json:"-"`
+}
+
+type QueryToken struct {
+  Text string
+  Position int
+}
+
+type QuerySyntaxError struct {
+  Message string
+  Position int
+}
+
+func (err QuerySyntaxError) Error() string {
+  return fmt.Sprint("q: ", err.Message, " at character ", err.Position)
+}
+
+type QueryParser struct {
+  Query string
+  Tokens []QueryToken
+  Next int
+  Search * Search
+}
+
+func IsQuoteRune(item rune) bool {
+  return IsQuote(string(item))
+}
+
+// parentheses and words; quoted text may contain spaces and parentheses
+func TokenizeQuery(query string) ([]QueryToken, error) {
+  tokens := make([]QueryToken, 0)
+  for i := 0; i < len(query); {
+    item, size := utf8.DecodeRuneInString(query[i:])
+    if unicode.IsSpace(item) {
+      i += size
+      continue
+    }
+    if item == '(' || item == ')' {
+      tokens = append(tokens, QueryToken{Text: query[i:i + 1], Position: i})
+      i++
+      continue
+    }
+    start := i
+    for i < len(query) {
+      item, size := utf8.DecodeRuneInString(query[i:])
+      if unicode.IsSpace(item) || item == '(' || item == ')' {
+        break
+      }
+      if IsQuoteRune(item) {
+        end := strings.IndexFunc(query[i + size:], IsQuoteRune)
+        if end == -1 {
+          return nil, QuerySyntaxError{"unterminated quote", QueryPosition(query, i)}
+        }
+        _, closing := utf8.DecodeRuneInString(query[i + size + end:])
+        i += size + end + closing
+        continue
+      }
+      i += size
+    }
+    tokens = append(tokens, QueryToken{Text: query[start:i], Position: start})
+  }
+  return tokens, nil
+}
+
+// 1 based character position
+func QueryPosition(query string, offset int) int {
+  return utf8.RuneCountInString(query[:offset]) + 1
+}
+
+func (search * Search) ParseQuery(query string) (* QueryNode, error) {
+  tokens, err := TokenizeQuery(query)
+  if err != nil {
+    return nil, err
+  }
+  if len(tokens) == 0 {
+    return nil, QuerySyntaxError{"empty query", 1}
+  }
+  parser := &QueryParser{Query: query, Tokens: tokens, Search: search}
+  node, err := parser.ParseOr()
+  if err == nil && parser.Next < len(tokens) {
+    err = parser.Unexpected()
+  }
+  return node, err
+}
+
+func (parser * QueryParser) Peek() string {
+  if parser.Next < len(parser.Tokens) {
+    return parser.Tokens[parser.Next].Text
+  }
+  return ""
+}
+
+func (parser * QueryParser) Unexpected() error {
+  if parser.Next >= len(parser.Tokens) {
+    return QuerySyntaxError{"unexpected end of query", QueryPosition(parser.Query, len(parser.Query))}
+  }
+  token := parser.Tokens[parser.Next]
+  return QuerySyntaxError{"unexpected '" + token.Text + "'", QueryPosition(parser.Query, token.Position)}
+}
+
+func (parser * QueryParser) ParseOr() (* QueryNode, error) {
+  node, err := parser.ParseAnd()
+  if err != nil {
+    return nil, err
+  }
+  children := []*QueryNode{node}
+  for parser.Peek() == "OR" {
+    parser.Next++
+    if node, err = parser.ParseAnd(); err != nil {
+      return nil, err
+    }
+    children = append(children, node)
+  }
+  if len(children) == 1 {
+    return children[0], nil
+  }
+  return &QueryNode{Operator: "or", Children: children}, nil
+}
+
+// AND may be left out between terms
+func (parser * QueryParser) ParseAnd() (* QueryNode, error) {
+  node, err := parser.ParseNot()
+  if err != nil {
+    return nil, err
+  }
+  children := []*QueryNode{node}
+  for {
+    next := parser.Peek()
+    if next == "AND" {
+      parser.Next++
+    } else if next == "" || next == "OR" || next == ")" {
+      break
+    }
+    if node, err = parser.ParseNot(); err != nil {
+      return nil, err
+    }
+    children = append(children, node)
+  }
+  if len(children) == 1 {
+    return children[0], nil
+  }
+  return &QueryNode{Operator: "and", Children: children}, nil
+}
+
+func (parser * QueryParser) ParseNot() (* QueryNode, error) {
+  if parser.Peek() == "NOT" {
+    parser.Next++
+    node, err := parser.ParseNot()
+    if err != nil {
+      return nil, err
+    }
+    return &QueryNode{Operator: "not", Children: []*QueryNode{node}}, nil
+  }
+  return parser.ParsePrimary()
+}
+
+func (parser * QueryParser) ParsePrimary() (* QueryNode, error) {
+  switch parser.Peek() {
+    case "(":
+      open := parser.Tokens[parser.Next]
+      parser.Next++
+      node, err := parser.ParseOr()
+      if err != nil {
+        return nil, err
+      }
+      if parser.Peek() != ")" {
+        if parser.Next >= len(parser.Tokens) {
+          return nil, QuerySyntaxError{"missing ')' for '('", QueryPosition(parser.Query, open.Position)}
+        }
+        return nil, parser.Unexpected()
+      }
+      parser.Next++
+      return node, nil
+    case "", ")", "AND", "OR":
+      return nil, parser.Unexpected()
+  }
+  token := parser.Tokens[parser.Next]
+  parser.Next++
+  return parser.ParseTerm(token)
+}
+
+// field:value, field:filter:value or text searched in every text field
+func (parser * QueryParser) ParseTerm(token QueryToken) (* QueryNode, error) {
+  search := parser.Search
+  parts := SplitUnquoted(token.Text, 3)
+  if len(parts) == 1 {
+    if _, has := search.Fields["search"]; !has {
+      return nil, QuerySyntaxError{"collection has no full text fields for '" + token.Text + "'", QueryPosition(parser.Query, token.Position)}
+    }
+    return &QueryNode{Field: "search", Filter: "search", Value: token.Text}, nil
+  }
+  field, has := search.Fields[parts[0]]
+  if !has || field.Filters == nil {
+    return nil, QuerySyntaxError{"unknown field '" + parts[0] + "'", QueryPosition(parser.Query, token.Position)}
+  }
+  node := &QueryNode{Field: parts[0], Filter: field.Filters[0], Value: parts[1]}
+  if len(parts) == 3 {
+    node.Filter = parts[1]
+    node.Value = parts[2]
+  }
+  if node.Value == "" {
+    return nil, QuerySyntaxError{"missing value for '" + parts[0] + "'", QueryPosition(parser.Query, token.Position + len(token.Text))}
+  }
+  // quotes group words of filter values; text search keeps them for phrases
+  if node.Filter != "search" {
+    if item, size := utf8.DecodeRuneInString(node.Value); IsQuoteRune(item) {
+      if last, lastSize := utf8.DecodeLastRuneInString(node.Value); IsQuoteRune(last) && len(node.Value) >= size + lastSize {
+        node.Value = node.Value[size:len(node.Value) - lastSize]
+      }
+    }
+  }
+  return node, nil
+}
+
+// splits on colons outside quotes into at most n parts
+func SplitUnquoted(str string, n int) []string {
+  parts := make([]string, 0, n)
+  quoted := false
+  start := 0
+  for i, item := range str {
+    if IsQuoteRune(item) {
+      quoted = !quoted
+    } else if item == ':' && !quoted && len(parts) < n - 1 {
+      parts = append(parts, str[start:i])
+      start = i + 1
+    }
+  }
+  return append(parts, str[start:])
+}
+
+// lowest entropy first as in QueryByEntropy; groups by their highest entropy,
+// optional clauses last
+func (search * Search) QueryEntropy(node * QueryNode) float64 {
+  if node.Operator == "" {
+    return search.Fields[node.Field].Entropy
+  }
+  if node.Operator == "optional" {
+    return math.Inf(1)
+  }
+  entropy := math.Inf(-1)
+  for _, child := range node.Children {
+    entropy = math.Max(entropy, search.QueryEntropy(child))
+  }
+  return entropy
+}
+
+func (search * Search) Evaluate(request * SearchRequest, schema * Schema, node * QueryNode, input SearchResults) SearchResults {
+  switch node.Operator {
+    case "and":
+      children := append([]*QueryNode(nil), node.Children...)
+      sort.SliceStable(children, func(i, j int) bool {
+        return search.QueryEntropy(children[i]) < search.QueryEntropy(children[j])
+      })
+      for _, child := range children {
+        if len(input) == 0 {
+          break
+        }
+        input = search.Evaluate(request, schema, child, input)
+      }
+      return input
+    case "or":
+      // filters work in place so each branch gets a copy; scores add up
+      scores := make(map[int]float64)
+      for _, child := range node.Children {
+        branch := append(SearchResults(nil), input...)
+        for _, x := range search.Evaluate(request, schema, child, branch) {
+          scores[x.Item] += x.Score
+        }
+      }
+      output := input[:0]
+      for _, x := range input {
+        if score, has := scores[x.Item]; has {
+          x.Score = score
+          output = append(output, x)
+        }
+      }
+      return output
+    case "optional":
+      // never filters; matches add their score
+      scores := make(map[int]float64)
+      for _, x := range search.Evaluate(request, schema, node.Children[0], append(SearchResults(nil), input...)) {
+        scores[x.Item] = x.Score
+      }
+      for i, x := range input {
+        input[i].Score += scores[x.Item]
+      }
+      return input
+    case "not":
+      matches := make(map[int]bool)
+      for _, x := range search.Evaluate(request, schema, node.Children[0], append(SearchResults(nil), input...)) {
+        matches[x.Item] = true
+      }
+      output := input[:0]
+      for _, x := range input {
+        if !matches[x.Item] {
+          output = append(output, x)
+        }
+      }
+      return output
+  }
+  if node.Predicate != nil {
+    return SearchPredicate(node.Predicate, input)
+  }
+  return search.ApplyFilter(request, schema, node.Field, node.Filter, node.Value, input)
+}
+
+func SearchPredicate(predicate func(int) bool, input SearchResults) SearchResults {
+  output := input[:0]
+  for _, x := range input {
+    if predicate(x.Item) {
+      output = append(output, x)
+    }
+  }
+  return output
+}
+
+// q parameters; syntax errors match nothing
+func (search * Search) FilterQuery(request * SearchRequest, schema * Schema, results SearchResults) SearchResults {
+  for _, query := range request.Queries["q"] {
+    node, err := search.ParseQuery(query)
+    if err != nil {
+      request.Errors = append(request.Errors, err.Error())
+      return results[:0]
+    }
+    request.Explain = append(request.Explain, map[string]interface{}{"q": query, "tree": node})
+    results = search.Evaluate(request, schema, node, results)
+  }
+  return results
+}
@@ -0,0 +1,79 @@
+package index
+
+import (
+  "encoding/json"
+  "net/url"
+  "reflect"
+  "testing"
+)
+
+func TestParseQuery(t *testing.T) {
+  search := FixtureCollection().search
+  leaf := func(field string, filter string, value string) * QueryNode {
+    return &QueryNode{Field: field, Filter: filter, Value: value}
+  }
+  node := func(operator string, children ...* QueryNode) * QueryNode {
+    return &QueryNode{Operator: operator, Children: children}
+  }
+  tests := []struct {
+    query string
+    want * QueryNode
+    err string
+  }{
+    {`color:red`, leaf("color", "within", "red"), ""},
+    {`price:lessThan:10`, leaf("price", "lessThan", "10"), ""},
+    {`television`, leaf("search", "search", "television"), ""},
+    {`brand:equals:"acme"`, leaf("brand", "equals", "acme"), ""},
+    {`title:search:"data loader"`, leaf("title", "search", `"data loader"`), ""},
+    {`color:red brand:acme`, node("and", leaf("color", "within", "red"), leaf("brand", "within", "acme")), ""},
+    {`color:red OR color:blue AND NOT brand:acme`, node("or", leaf("color", "within", "red"), node("and", leaf("color", "within", "blue"), node("not", leaf("brand", "within", "acme")))), ""},
+    {`(color:red OR color:blue) AND price:lessThan:10`, node("and", node("or", leaf("color", "within", "red"), leaf("color", "within", "blue")), leaf("price", "lessThan", "10")), ""},
+    {`NOT NOT flag:true`, node("not", node("not", leaf("flag", "equals", "true"))), ""},
+    {``, nil, "q: empty query at character 1"},
+    {`   `, nil, "q: empty query at character 1"},
+    {`nope:1`, nil, "q: unknown field 'nope' at character 1"},
+    {`color:`, nil, "q: missing value for 'color' at character 7"},
+    {`(color:red`, nil, "q: missing ')' for '(' at character 1"},
+    {`color:red)`, nil, "q: unexpected ')' at character 10"},
+    {`color:red AND`, nil, "q: unexpected end of query at character 14"},
+    {`OR color:red`, nil, "q: unexpected 'OR' at character 1"},
+    {`brand:"acme`, nil, "q: unterminated quote at character 7"},
+    {`é:1 nope:1`, nil, "q: unknown field 'é' at character 1"},
+    {`color:red nope:1`, nil, "q: unknown field 'nope' at character 11"},
+  }
+  for _, test := range tests {
+    got, err := search.ParseQuery(test.query)
+    if test.err != "" {
+      if err == nil || err.Error() != test.err {
+        t.Errorf("ParseQuery(%q) error = %v, want %q", test.query, err, test.err)
+      }
+    } else if err != nil || !reflect.DeepEqual(got, test.want) {
+      encoded, _ := json.Marshal(got)
+      t.Errorf("ParseQuery(%q) = %s, %v", test.query, encoded, err)
+    }
+  }
+}
+
+func TestSearchQuery(t *testing.T) {
+  collection := FixtureCollection()
+  tests := []struct {
+    q string
+    total int
+  }{
+    {`color:red`, 134},
+    {`color:red OR color:blue`, 267},
+    {`color:red color:blue`, 0},
+    {`NOT color:red`, 266},
+    {`(color:red OR color:blue) AND brand:acme`, 67},
+    {`television`, 134},
+    {`NOT price:exists:true`, 58},
+  }
+  for _, test := range tests {
+    output := collection.search.Search(url.Values{"q": {test.q}}, collection.schema)
+    if errors, has := output["errors"]; has {
+      t.Errorf("q=%s: %v", test.q, errors)
+    } else if output["total"] != test.total {
+      t.Errorf("q=%s total = %v, want %d", test.q, output["total"], test.total)
+    }
+  }
+}
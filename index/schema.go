@@ -12,6 +12,7 @@ type Schema struct {
   Properties map[string]SchemaField `json:"properties,omitempty"`
   TotalItems int `json:"-"`
   SummaryFields []string `json:"-"`
+  DefaultFields * Projection `json:"-"` // nil for the summary or every field
 }
 
 type SchemaField struct {
@@ -19,6 +20,7 @@ type SchemaField struct {
   
   // internal; use less memory
   Entropy float64 `json:"entropy"`
+  NullCount int `json:"nullCount"` // records without a value
   UniqueValues []interface{} `json:"-"`
   ValueIndex []int `json:"-"`
   
@@ -30,6 +32,9 @@ type SchemaField struct {
   HasSpace bool `json:"-"`
   AllUnique bool `json:"-"`
   OutValues interface{} `json:"enum,omitempty"`
+  
+  // vector
+  Dimensions int `json:"dimensions,omitempty"`
 }
 
 func (s Schema) Len() int {
@@ -40,8 +45,13 @@ func (s Schema) Swap(i, j int) {
   s.SummaryFields[i], s.SummaryFields[j] = s.SummaryFields[j], s.SummaryFields[i]
 }
 
+// ties by name, reversed so that summaries list them alphabetically
 func (s Schema) Less(i, j int) bool {
-  return s.Properties[s.SummaryFields[i]].Entropy < s.Properties[s.SummaryFields[j]].Entropy
+  a, b := s.Properties[s.SummaryFields[i]].Entropy, s.Properties[s.SummaryFields[j]].Entropy
+  if a == b {
+    return s.SummaryFields[i] > s.SummaryFields[j]
+  }
+  return a < b
 }
 
 func (schema *Schema) Initialise (data map[string][]interface{}) {
@@ -69,6 +79,9 @@ func (schema *Schema) Initialise (data map[string][]interface{}) {
           case "string":
             InitialiseStringField(field, fieldData, schema);
             break;
+          case "vector":
+            InitialiseVectorField(field, fieldData, schema);
+            break;
           default:
             InitialiseMiscField(field, fieldType, fieldData, schema);
         };
@@ -81,10 +94,32 @@ func (schema *Schema) Initialise (data map[string][]interface{}) {
   
   wait.Wait()
   
-  sort.Reverse(schema)
+  for field, fieldData := range schema.Properties {
+    for _, code := range fieldData.ValueIndex {
+      if code == -1 {
+        fieldData.NullCount++
+      }
+    }
+    schema.Properties[field] = fieldData
+  }
+  
+  sort.Sort(sort.Reverse(schema))
   fmt.Println("Bootstrapping schema... done.")
 }
 
+// replaces the entropy ordered summary fields; unknown fields are skipped
+func (schema * Schema) SetSummaryFields(fields []string) {
+  summaryFields := make([]string, 0, len(fields))
+  for _, field := range fields {
+    if _, has := schema.Properties[field]; has {
+      summaryFields = append(summaryFields, field)
+    } else {
+      fmt.Println("Unknown summary field", field)
+    }
+  }
+  schema.SummaryFields = summaryFields
+}
+
 func (schema * Schema) GetSummary(index int, fields int) map[string]interface{} {
   if fields + 1 > len(schema.SummaryFields) {
     fields = len(schema.SummaryFields) - 1
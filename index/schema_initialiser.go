@@ -17,6 +17,9 @@ func DetermineFieldType(data * []interface{}) string {
     if value != nil {
       switch reflect.TypeOf(value).Kind().String() {
         case "slice":
+          if VectorDimensions(data) > 0 {
+            return "vector"
+          }
           return "array"
         case "map":
           return "object"
@@ -32,6 +35,27 @@ func DetermineFieldType(data * []interface{}) string {
   return "null"
 }
 
+// length of fixed length number arrays (embeddings), otherwise 0
+func VectorDimensions(data * []interface{}) int {
+  dimensions := 0
+  for _, value := range *data {
+    if value == nil {
+      continue
+    }
+    array, isArray := value.([]interface{})
+    if !isArray || len(array) < 2 || (dimensions > 0 && len(array) != dimensions) {
+      return 0
+    }
+    for _, x := range array {
+      if _, isNumber := x.(float64); !isNumber {
+        return 0
+      }
+    }
+    dimensions = len(array)
+  }
+  return dimensions
+}
+
 func ExtractStringsFromJson(value interface{}) string {
   switch reflect.TypeOf(value).Kind().String() {
     case "slice":
@@ -54,6 +78,33 @@ func ExtractStringsFromJson(value interface{}) string {
   return "";
 }
 
+// strings of a value, one per array element or object property
+func ExtractStringListFromJson(value interface{}) []string {
+  switch reflect.TypeOf(value).Kind().String() {
+    case "slice":
+      output := make([]string, 0)
+      for _, x := range value.([]interface{}) {
+        output = append(output, ExtractStringListFromJson(x)...)
+      }
+      return output
+    case "map":
+      object := value.(map[string]interface{})
+      keys := make([]string, 0, len(object))
+      for key, _ := range object {
+        keys = append(keys, key)
+      }
+      sort.Strings(keys)
+      output := make([]string, 0)
+      for _, key := range keys {
+        output = append(output, ExtractStringListFromJson(object[key])...)
+      }
+      return output
+    case "string":
+      return []string{value.(string)}
+  }
+  return nil
+}
+
 var schemaMutex sync.RWMutex
 
 func (schema * Schema) AddField(field string, fieldData SchemaField, addToSummary bool) {
@@ -72,27 +123,27 @@ func InitialiseBooleanField(field string, fieldData []interface {}, schema *Sche
   
   trueValues := 0.0
   falseValues := 0.0
+  missingValues := 0.0
   for index, value := range fieldData {
     if value == nil {
-      ValueIndex[index] = 0
-    } else {
-      if value.(bool) {
-        ValueIndex[index] = 0
-      } else {
-        ValueIndex[index] = 1
-      }
-    }
-    if ValueIndex[index] == 1 {
+      ValueIndex[index] = -1
+      missingValues += 1.0
+    } else if value.(bool) {
+      ValueIndex[index] = 1
       trueValues += 1.0
     } else {
+      ValueIndex[index] = 0
       falseValues += 1.0
     }
   }
   Entropy := 0.0
   Total := float64(len(ValueIndex))
   
-  Entropy -= trueValues / Total * math.Log2(trueValues / Total)
-  Entropy -= falseValues / Total * math.Log2(falseValues / Total)
+  for _, count := range []float64{trueValues, falseValues, missingValues} {
+    if count > 0 {
+      Entropy -= count / Total * math.Log2(count / Total)
+    }
+  }
   
   schema.AddField(field, SchemaField{Type: "boolean", Entropy: Entropy, UniqueValues: UniqueValues, ValueIndex: ValueIndex}, true)
 }
@@ -111,16 +162,28 @@ func InitialiseNumberField(field string, fieldData []interface {}, schema *Schem
   maxValue := UniqueValues[len(UniqueValues) - 1]
   
   ValueIndex := make([]int, len(fieldData))
+  counts := make([]float64, len(UniqueValues))
+  totalValues := 0.0
   
   for index, value := range fieldData {
     if value == nil {
       ValueIndex[index] = -1
     } else {
       ValueIndex[index] = UniqueValues.IndexOf(value.(float64))
+      counts[ValueIndex[index]] += 1
+      totalValues += 1
+    }
+  }
+  
+  // normalised as for strings; a single value carries no information
+  Entropy := 0.0
+  if len(counts) > 1 {
+    for _, count := range counts {
+      Entropy -= count / totalValues * math.Log2(count / totalValues) / math.Log2(float64(len(counts)))
     }
   }
   
-  schema.Properties[field] = SchemaField{Type: "number", UniqueValues: UniqueValues.Trim(), ValueIndex: ValueIndex, MinValue: minValue, MaxValue: maxValue}
+  schema.AddField(field, SchemaField{Type: "number", UniqueValues: UniqueValues.Trim(), ValueIndex: ValueIndex, Entropy: Entropy, MinValue: minValue, MaxValue: maxValue}, true)
 }
 
 func InitialiseStringField(field string, fieldData []interface {}, schema *Schema) {
@@ -133,7 +196,7 @@ func InitialiseStringField(field string, fieldData []interface {}, schema *Schem
 
   for _, value := range fieldData {
     if value != nil && value.(string) != EMPTY {
-      uniqueValues[value.(string)] = uniqueValues[EMPTY] + 1
+      uniqueValues[value.(string)] += 1
       totalValues++
     }
   }
@@ -158,9 +221,12 @@ func InitialiseStringField(field string, fieldData []interface {}, schema *Schem
     if ValueCount > 1 {
       Entropy -= count / totalValues * math.Log2(count / totalValues) / math.Log2(float64(ValueCount))
     } else {
+      // present or missing
       ValueCount = float64(len(fieldData))
       Entropy -= count / ValueCount * math.Log2(count / ValueCount)
-      Entropy -= (ValueCount - count) / ValueCount * math.Log2((ValueCount - count) / ValueCount)
+      if count < ValueCount {
+        Entropy -= (ValueCount - count) / ValueCount * math.Log2((ValueCount - count) / ValueCount)
+      }
     }
   }
   
@@ -221,3 +287,26 @@ func InitialiseMiscField(field string, fieldType string, fieldData []interface {
   
   schema.AddField(field, SchemaField{Type: fieldType, Entropy: 0, UniqueValues: UniqueValues, ValueIndex: ValueIndex}, false)
 }
+
+func InitialiseVectorField(field string, fieldData []interface {}, schema *Schema) {
+  
+  Dimensions := VectorDimensions(&fieldData)
+  UniqueValues := make([]interface{}, 0, len(fieldData))
+  ValueIndex := make([]int, len(fieldData))
+  
+  for index, value := range fieldData {
+    if value != nil {
+      array := value.([]interface{})
+      vector := make([]float64, len(array))
+      for i, x := range array {
+        vector[i] = x.(float64)
+      }
+      ValueIndex[index] = len(UniqueValues)
+      UniqueValues = append(UniqueValues, vector)
+    } else {
+      ValueIndex[index] = -1
+    }
+  }
+  
+  schema.AddField(field, SchemaField{Type: "vector", Entropy: 0, UniqueValues: UniqueValues, ValueIndex: ValueIndex, Dimensions: Dimensions}, false)
+}
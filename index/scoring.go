@@ -0,0 +1,196 @@
+package index
+
+import (
+  "errors"
+  "math"
+  "strconv"
+  "strings"
+  "time"
+)
+
+// function scores combine _score with a field value:
+//   linear:field[:factor]              _score * factor * value
+//   log:field[:factor]                 _score * ln(1 + factor * value)
+//   decay:field:origin:scale[:decay]   _score * decay ^ (|value - origin| / scale)
+// records without a value score 0
+
+var DATE_LAYOUTS = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+func ParseDate(str string) (time.Time, bool) {
+  for _, layout := range DATE_LAYOUTS {
+    if value, err := time.Parse(layout, str); err == nil {
+      return value, true
+    }
+  }
+  return time.Time{}, false
+}
+
+// numbers as they are; dates as unix seconds
+func ScoreAccessor(field SchemaField) (func(int) (float64, bool), bool) {
+  switch field.Type {
+    case "number":
+      return NumberAccessor(field), false
+    case "string":
+      values := make([]float64, len(field.UniqueValues))
+      valid := make([]bool, len(field.UniqueValues))
+      for i, value := range field.UniqueValues {
+        if date, isDate := ParseDate(value.(string)); isDate {
+          values[i] = float64(date.Unix())
+          valid[i] = true
+        }
+      }
+      valueIndex := field.ValueIndex
+      return func(x int) (float64, bool) {
+        xx := valueIndex[x]
+        if xx != -1 && valid[xx] {
+          return values[xx], true
+        }
+        return 0, false
+      }, true
+  }
+  return nil, false
+}
+
+// days and weeks on top of go durations, in seconds
+func ParseDateScale(str string) (float64, error) {
+  units := map[string]float64{"d": 86400, "w": 604800}
+  for unit, seconds := range units {
+    if strings.HasSuffix(str, unit) {
+      value, err := strconv.ParseFloat(str[:len(str) - 1], 64)
+      return value * seconds, err
+    }
+  }
+  duration, err := time.ParseDuration(str)
+  return duration.Seconds(), err
+}
+
+func ParseScoreFunction(spec string, schema * Schema) (func(int) float64, error) {
+  parts := strings.Split(spec, ":")
+  if len(parts) < 2 {
+    return nil, errors.New("score '" + spec + "' is not supported")
+  }
+  function := parts[0]
+  field, has := schema.Properties[parts[1]]
+  if !has {
+    return nil, errors.New("score field '" + parts[1] + "' does not exist")
+  }
+  accessor, isDate := ScoreAccessor(field)
+  if accessor == nil {
+    return nil, errors.New("score field '" + parts[1] + "' is not a number or date")
+  }
+  number := func(i int, fallback float64) (float64, error) {
+    if len(parts) <= i {
+      return fallback, nil
+    }
+    value, err := strconv.ParseFloat(parts[i], 64)
+    if err != nil {
+      err = errors.New("score '" + spec + "' value '" + parts[i] + "' is not a number")
+    }
+    return value, err
+  }
+  switch function {
+    case "linear", "log":
+      factor, err := number(2, 1)
+      if err != nil {
+        return nil, err
+      }
+      return func(x int) float64 {
+        value, has := accessor(x)
+        if !has {
+          return 0
+        }
+        if function == "log" {
+          return math.Log1p(math.Max(factor * value, 0))
+        }
+        return factor * value
+      }, nil
+    case "decay":
+      // date origins may contain colons themselves
+      rest := parts[2:]
+      isOrigin := func(origin string) bool {
+        if isDate {
+          _, ok := ParseDate(origin)
+          return ok || origin == "now"
+        }
+        _, err := strconv.ParseFloat(origin, 64)
+        return err == nil
+      }
+      decay := 0.5
+      if len(rest) >= 3 && isOrigin(strings.Join(rest[:len(rest) - 2], ":")) {
+        var err error
+        if decay, err = number(len(parts) - 1, 0.5); err != nil {
+          return nil, err
+        }
+        rest = rest[:len(rest) - 1]
+      }
+      if len(rest) < 2 {
+        return nil, errors.New("score '" + spec + "' requires origin and scale")
+      }
+      originStr, scaleStr := strings.Join(rest[:len(rest) - 1], ":"), rest[len(rest) - 1]
+      var origin, scale float64
+      var err error
+      if isDate {
+        if originStr == "now" {
+          origin = float64(time.Now().Unix())
+        } else if date, ok := ParseDate(originStr); ok {
+          origin = float64(date.Unix())
+        } else {
+          return nil, errors.New("score '" + spec + "' origin '" + originStr + "' is not a date")
+        }
+        scale, err = ParseDateScale(scaleStr)
+      } else {
+        if origin, err = strconv.ParseFloat(originStr, 64); err != nil {
+          return nil, errors.New("score '" + spec + "' origin '" + originStr + "' is not a number")
+        }
+        scale, err = strconv.ParseFloat(scaleStr, 64)
+      }
+      if err != nil || scale <= 0 {
+        return nil, errors.New("score '" + spec + "' scale '" + scaleStr + "' is not supported")
+      }
+      if decay <= 0 || decay >= 1 {
+        return nil, errors.New("score '" + spec + "' decay must be between 0 and 1")
+      }
+      return func(x int) float64 {
+        value, has := accessor(x)
+        if !has {
+          return 0
+        }
+        return math.Pow(decay, math.Abs(value - origin) / scale)
+      }, nil
+  }
+  return nil, errors.New("score function '" + function + "' is not supported")
+}
+
+func ScoreResults(function func(int) float64, input SearchResults) SearchResults {
+  for i, x := range input {
+    input[i].Score = x.Score * function(x.Item)
+  }
+  return input
+}
+
+// boost=title:3,body:0.5
+func ParseBoosts(values []string, search * Search) (map[string]float64, []string) {
+  boosts := make(map[string]float64)
+  errors := make([]string, 0)
+  for _, value := range values {
+    for _, pair := range strings.Split(value, ",") {
+      i := strings.LastIndex(pair, ":")
+      if i == -1 {
+        errors = append(errors, "boost '" + pair + "' is not supported")
+        continue
+      }
+      field := pair[:i]
+      weight, err := strconv.ParseFloat(pair[i+1:], 64)
+      if _, has := search.TextIndexes[field]; !has || err != nil {
+        errors = append(errors, "boost '" + pair + "' is not supported")
+        continue
+      }
+      if weight < 0 {
+        errors = append(errors, "boost '" + pair + "' must not be negative")
+        continue
+      }
+      boosts[field] = weight
+    }
+  }
+  return boosts, errors
+}
@@ -0,0 +1,147 @@
+package index
+
+import (
+  "math"
+  "net/url"
+  "reflect"
+  "testing"
+)
+
+func TestParseBoosts(t *testing.T) {
+  search := FixtureCollection().search
+  tests := []struct {
+    values []string
+    boosts map[string]float64
+    errors []string
+  }{
+    {[]string{"title:3"}, map[string]float64{"title": 3}, []string{}},
+    {[]string{"title:0"}, map[string]float64{"title": 0}, []string{}},
+    {[]string{"title:2", "title:0.5"}, map[string]float64{"title": 0.5}, []string{}},
+    {[]string{"title:-1"}, map[string]float64{}, []string{"boost 'title:-1' must not be negative"}},
+    {[]string{"color:2,title:1"}, map[string]float64{"title": 1}, []string{"boost 'color:2' is not supported"}},
+    {[]string{"title"}, map[string]float64{}, []string{"boost 'title' is not supported"}},
+    {[]string{"title:x"}, map[string]float64{}, []string{"boost 'title:x' is not supported"}},
+  }
+  for _, test := range tests {
+    boosts, errors := ParseBoosts(test.values, search)
+    if !reflect.DeepEqual(boosts, test.boosts) || !reflect.DeepEqual(errors, test.errors) {
+      t.Errorf("ParseBoosts(%q) = %v, %q, want %v, %q", test.values, boosts, errors, test.boosts, test.errors)
+    }
+  }
+}
+
+// a boost of 0 keeps the matches and only changes their score
+func TestBoostScores(t *testing.T) {
+  collection := FixtureCollection()
+  tests := []struct {
+    boost string
+    factor float64
+  }{
+    {"", 1},
+    {"title:2", 2},
+    {"title:0.5", 0.5},
+    {"title:0", 0},
+  }
+  var base float64
+  for _, test := range tests {
+    queries := url.Values{"search": {"television"}, "limit": {"1"}}
+    if test.boost != "" {
+      queries.Set("boost", test.boost)
+    }
+    output := collection.search.Search(queries, collection.schema)
+    results := output["results"].([]map[string]interface{})
+    if output["errors"] != nil || output["total"] != 134 || len(results) != 1 {
+      t.Errorf("boost=%s: total = %v, errors = %v, want 134", test.boost, output["total"], output["errors"])
+      continue
+    }
+    score := results[0]["_score"].(float64)
+    if test.boost == "" {
+      base = score
+    }
+    if math.Abs(score - base * test.factor) > 1e-12 {
+      t.Errorf("boost=%s: score = %v, want %v", test.boost, score, base * test.factor)
+    }
+  }
+}
+
+func TestParseScoreFunction(t *testing.T) {
+  schema := FixtureCollection().schema
+  tests := []struct {
+    spec string
+    item int
+    want float64
+    err string
+  }{
+    {"linear:popularity", 10, 10, ""},
+    {"linear:popularity:2", 10, 20, ""},
+    {"log:popularity", 10, math.Log(11), ""},
+    {"log:popularity:-1", 10, 0, ""},
+    {"decay:popularity:100:50", 10, math.Pow(0.5, 1.8), ""},
+    {"decay:popularity:100:50", 190, math.Pow(0.5, 1.8), ""},
+    {"decay:popularity:100:50", 100, 1, ""},
+    {"decay:popularity:100:50:0.25", 10, math.Pow(0.25, 1.8), ""},
+    {"decay:popularity:-10:5", 0, 0.25, ""},
+    // records without a value score 0
+    {"linear:price", 7, 0, ""},
+    {"decay:price:0:10", 7, 0, ""},
+    {"linear", 0, 0, "score 'linear' is not supported"},
+    {"linear:nope", 0, 0, "score field 'nope' does not exist"},
+    {"linear:flag", 0, 0, "score field 'flag' is not a number or date"},
+    {"linear:popularity:x", 0, 0, "score 'linear:popularity:x' value 'x' is not a number"},
+    {"cube:popularity", 0, 0, "score function 'cube' is not supported"},
+    {"decay:popularity:100", 0, 0, "score 'decay:popularity:100' requires origin and scale"},
+    {"decay:popularity:100:0", 0, 0, "score 'decay:popularity:100:0' scale '0' is not supported"},
+    {"decay:popularity:100:-5", 0, 0, "score 'decay:popularity:100:-5' scale '-5' is not supported"},
+    {"decay:popularity:x:50", 0, 0, "score 'decay:popularity:x:50' origin 'x' is not a number"},
+    {"decay:popularity:100:50:1", 0, 0, "score 'decay:popularity:100:50:1' decay must be between 0 and 1"},
+    {"decay:popularity:100:50:0", 0, 0, "score 'decay:popularity:100:50:0' decay must be between 0 and 1"},
+  }
+  for _, test := range tests {
+    function, err := ParseScoreFunction(test.spec, schema)
+    if test.err != "" {
+      if err == nil || err.Error() != test.err {
+        t.Errorf("ParseScoreFunction(%q) error = %v, want %q", test.spec, err, test.err)
+      }
+      continue
+    }
+    if err != nil {
+      t.Errorf("ParseScoreFunction(%q) error = %v", test.spec, err)
+    } else if got := function(test.item); math.Abs(got - test.want) > 1e-12 {
+      t.Errorf("ParseScoreFunction(%q)(%d) = %v, want %v", test.spec, test.item, got, test.want)
+    }
+  }
+}
+
+// date fields decay by their distance in seconds; origins may hold colons
+func TestDateDecay(t *testing.T) {
+  collection := Index(map[string][]interface{}{
+    "published": {"2024-01-01", "2024-01-31", "2023-12-02T00:00:00Z", "not a date", nil},
+  }, Options{})
+  collection.Wait()
+  tests := []struct {
+    spec string
+    want []float64
+  }{
+    {"decay:published:2024-01-01:30d", []float64{1, 0.5, 0.5, 0, 0}},
+    {"decay:published:2024-01-01T00:00:00:30d:0.25", []float64{1, 0.25, 0.25, 0, 0}},
+    {"decay:published:2024-01-01:720h", []float64{1, 0.5, 0.5, 0, 0}},
+    {"decay:published:2024-01-16:15d", []float64{0.5, 0.5, math.Pow(0.5, 45.0 / 15), 0, 0}},
+  }
+  for _, test := range tests {
+    function, err := ParseScoreFunction(test.spec, collection.schema)
+    if err != nil {
+      t.Errorf("ParseScoreFunction(%q) error = %v", test.spec, err)
+      continue
+    }
+    for item, want := range test.want {
+      if got := function(item); math.Abs(got - want) > 1e-12 {
+        t.Errorf("ParseScoreFunction(%q)(%d) = %v, want %v", test.spec, item, got, want)
+      }
+    }
+  }
+  for _, spec := range []string{"decay:published:yesterday:30d", "decay:published:2024-01-01:soon"} {
+    if _, err := ParseScoreFunction(spec, collection.schema); err == nil {
+      t.Errorf("ParseScoreFunction(%q) gave no error", spec)
+    }
+  }
+}
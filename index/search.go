@@ -9,37 +9,130 @@ import (
   "strconv"
   "strings"
   "sync"
+  "time"
 )
 
 const RESULTS_TO_RETURN int = 20
 
+// notEquals is also accepted for notEqual
+var NUMBER_FILTERS = []string{"equals", "notEqual", "lessThan", "lessThanOrEqual", "greaterThan", "greaterThanOrEqual", "between", "in"}
+
+// literal string filters; each has an IgnoreCase variant, e.g. prefixIgnoreCase
+var STRING_FILTERS = []string{
+  "equals", "in", "notIn", "prefix", "suffix", "contains",
+  "equalsIgnoreCase", "inIgnoreCase", "notInIgnoreCase", "prefixIgnoreCase", "suffixIgnoreCase", "containsIgnoreCase",
+}
+
+// query parameters that are not field filters; fields with these names are
+// not searchable
+var RESERVED_PARAMETERS = []string{
+  "q", "search", "sort", "limit", "offset", "cursor", "fields", "facets", "facet_exclude", "aggs",
+  "highlight", "highlight_fields", "highlight_pre", "highlight_post", "highlight_encoder", "collapse", "score", "boost", "explain",
+  "k", "similar_to", "vector_field", "metric", "threshold", "by", "agg",
+}
+
+// value of each filter, listed as filterValues in the search metadata;
+// IgnoreCase variants take the same values
+var FILTER_VALUES = map[string]string{
+  "equals": "the value",
+  "notEqual": "a number",
+  "lessThan": "a number",
+  "lessThanOrEqual": "a number",
+  "greaterThan": "a number",
+  "greaterThanOrEqual": "a number",
+  "between": "a range such as [10,20), (10,20] or [10,",
+  "in": "comma separated values",
+  "notIn": "comma separated values",
+  "prefix": "the start of the value",
+  "suffix": "the end of the value",
+  "contains": "part of the value",
+  "within": "a regular expression matching the whole value, e.g. red|blue",
+  "notWithin": "a regular expression as for within; matches the values within does not",
+  "regex": "a regular expression matching part of the value",
+  "search": "full text query",
+  "exists": "ignored",
+  "missing": "ignored",
+}
+
 const ENUMERATE_THRESHOLD_FRACTION float64 = 0.01
 const ENUMERATE_THRESHOLD_COUNT float64 = 100
 
 type Search struct {
   Fields map[string]SearchField `json:"fields,omitempty"`
+  FilterValues map[string]string `json:"filterValues,omitempty"`
   Sort []string `json:"sort,omitempty"`
   
-  // full text index
-  TextIndex []string `json:"-"`
-  TextIndexWordCounts map[string]int `json:"-"`
+  // full text index per field
+  TextIndexes map[string]*TextIndex `json:"-"`
+  TextWeights map[string]float64 `json:"-"`
+  HighlightPre string `json:"-"`
+  HighlightPost string `json:"-"`
+  Synonyms * Synonyms `json:"-"`
+  Analyzer Analyzer `json:"-"`
+  FieldLanguages map[string]*Language `json:"-"`
+  LanguageField string `json:"-"`
+  RecordLanguages []*Language `json:"-"`
+  TextLanguages []*Language `json:"-"`
+  
+  VectorIndexes map[string]*VectorIndex `json:"-"`
+  
+  // near duplicates; clusters are found on first use
+  DuplicateThreshold float64 `json:"-"`
+  Duplicates DuplicateIndex `json:"-"`
   Lock sync.RWMutex `json:"-"`
+  
+  // fields left out of search; include lists the only searchable fields
+  IncludeFields map[string]bool `json:"-"`
+  ExcludeFields map[string]bool `json:"-"`
+  
+  // page size cap; cursors are refused once the index version changes and
+  // keep the first CursorWindow results of a search
+  MaxLimit int `json:"maxLimit"`
+  Version string `json:"-"`
+  Cursors CursorCache `json:"-"`
+  CursorWindow int `json:"-"`
 }
 
 type SearchField struct {
   Entropy float64 `json:"entropy"`
   Filters []string `json:"filters,omitempty"`
+  Fields []string `json:"fields,omitempty"`
+  Weight float64 `json:"weight,omitempty"`
   OutValues interface{} `json:"enum,omitempty"`
   MinValue float64 `json:"minValue,omitempty"`
   MaxValue float64 `json:"maxValue,omitempty"`
+  Dimensions int `json:"dimensions,omitempty"`
+  Metrics []string `json:"metrics,omitempty"`
+  NullCount int `json:"nullCount"`
 }
 
 func (search * Search) Initialise (schema * Schema) {
   search.Fields = make(map[string]SearchField);
   
-  for _, property := range schema.SummaryFields[:5] {
+  for _, parameter := range RESERVED_PARAMETERS {
+    if _, has := schema.Properties[parameter]; has {
+      fmt.Println("Field '" + parameter + "' has the name of a query parameter and is not searchable")
+    }
+  }
+  
+  properties := make([]string, 0, len(schema.Properties))
+  for property, _ := range schema.Properties {
+    if search.Searchable(property) {
+      properties = append(properties, property)
+    }
+  }
+  sort.Strings(properties)
+  
+  for _, property := range properties {
     propertyData := schema.Properties[property]
     
+    switch propertyData.Type {
+      case "boolean", "number", "string":
+        break;
+      default:
+        continue
+    }
+    
     fmt.Print("Bootstrapping search... ", property, " " , propertyData.Type);
     
     switch propertyData.Type {
@@ -59,20 +152,55 @@ func (search * Search) Initialise (schema * Schema) {
     fmt.Println(";");
   }
   
+  search.FilterValues = make(map[string]string, len(FILTER_VALUES))
+  for filter, value := range FILTER_VALUES {
+    search.FilterValues[filter] = value
+  }
+  for _, filter := range STRING_FILTERS {
+    if base := strings.TrimSuffix(filter, "IgnoreCase"); base != filter {
+      search.FilterValues[filter] = FILTER_VALUES[base] + ", ignoring case"
+    }
+  }
+  
+  search.Sort = []string{"_score"}
+  for _, property := range properties {
+    if Sortable(schema.Properties[property]) {
+      search.Sort = append(search.Sort, property)
+    }
+  }
+  
   IndexFullText(schema, search);
   
+  IndexVectorFields(schema, search);
+  
+  IndexNullFilters(schema, search);
+  
+  search.Version = strconv.FormatInt(time.Now().UnixNano(), 36)
+  
   runtime.GC();
   
   fmt.Println("Bootstrapping search... done.")
 }
 
 
+func (search * Search) Searchable(field string) bool {
+  for _, parameter := range RESERVED_PARAMETERS {
+    if field == parameter {
+      return false
+    }
+  }
+  if len(search.IncludeFields) > 0 && !search.IncludeFields[field] {
+    return false
+  }
+  return !search.ExcludeFields[field]
+}
+
 func IndexBooleanField(field string, fieldData * SchemaField, search *Search) {
   search.Fields[field] = SearchField{Filters: []string{"equals"}, OutValues: []string{"true", "false"}, Entropy: fieldData.Entropy}
 }
 
 func IndexNumberField(field string, fieldData * SchemaField, search *Search) {
-  search.Fields[field] = SearchField{Filters: []string{"equals", "lessThan", "greaterThan", "notEquals"}, MinValue: fieldData.MinValue, MaxValue: fieldData.MaxValue, Entropy: fieldData.Entropy}
+  search.Fields[field] = SearchField{Filters: NUMBER_FILTERS, MinValue: fieldData.MinValue, MaxValue: fieldData.MaxValue, Entropy: fieldData.Entropy}
 }
 
 func IndexStringField(field string, fieldData * SchemaField, search *Search) {
@@ -86,203 +214,481 @@ func IndexStringField(field string, fieldData * SchemaField, search *Search) {
   if UniqueValuesFraction <= ENUMERATE_THRESHOLD_FRACTION && UniqueValuesCount <= ENUMERATE_THRESHOLD_COUNT {
     fieldData.OutValues = fieldData.UniqueValues
     OutValues = fieldData.UniqueValues
-    Filters = append(Filters, "within")
+    Filters = append(Filters, "within", "notWithin")
   } else {
     Filters = append(Filters, "regex")
   }
+  Filters = append(Filters, STRING_FILTERS...)
   
   search.Fields[field] = SearchField{Filters: Filters, OutValues: OutValues, Entropy: fieldData.Entropy}
 }
 
+// every field can be filtered on whether it has a value
+func IndexNullFilters(schema * Schema, search *Search) {
+  for field, fieldData := range schema.Properties {
+    if !search.Searchable(field) {
+      continue
+    }
+    searchField, has := search.Fields[field]
+    if !has {
+      searchField = SearchField{Entropy: fieldData.Entropy}
+    }
+    searchField.Filters = append(append([]string(nil), searchField.Filters...), "exists", "missing")
+    searchField.NullCount = fieldData.NullCount
+    search.Fields[field] = searchField
+  }
+}
+
 func IndexFullText(schema * Schema, search *Search) {
   
   fields := make(map[string]SchemaField, 5)
   
   for field, fieldData := range schema.Properties {
+    if !search.Searchable(field) {
+      continue
+    }
     if fieldData.Type == "string" && fieldData.HasSpace {
       UniqueValuesCount := float64(len(fieldData.UniqueValues))
       UniqueValuesFraction := UniqueValuesCount / float64(len(fieldData.ValueIndex))
       if UniqueValuesFraction > ENUMERATE_THRESHOLD_FRACTION && UniqueValuesCount > ENUMERATE_THRESHOLD_COUNT {
         fields[field] = fieldData;
       }
-    } else if fieldData.Type == "array" || fieldData.Type == "map" {
+    } else if fieldData.Type == "array" || fieldData.Type == "object" {
       fields[field] = fieldData;
     }
   }
   
   if len(fields) > 0 {
     fmt.Print("Bootstrapping search... ", "search fulltext:");
-    stemmed := make([]string, schema.TotalItems)
-    for field, fieldData := range fields {
-      if fieldData.Type == "string" {
-        getValue := StringAccessor(fieldData)
-        fmt.Print(" ", field);
-        for i := 0; i < schema.TotalItems; i++ {
-          if value, has := getValue(i); has {
-            stemmed[i] += PadWithSpace(LexAndStem(value))
-          }
+    if languageField, has := schema.Properties[search.LanguageField]; has && languageField.Type == "string" {
+      getLanguage := StringAccessor(languageField)
+      search.RecordLanguages = make([]*Language, schema.TotalItems)
+      for i := 0; i < schema.TotalItems; i++ {
+        if value, has := getLanguage(i); has {
+          search.RecordLanguages[i] = LookupLanguage(value)
         }
-      } else {
-        fmt.Print(" ", field);
-        getValue := GenericAccessor(fieldData)
-        for i := 0; i < schema.TotalItems; i++ {
-          if value, has := getValue(i); has {
-            strValue := ExtractStringsFromJson(value)
-            stemmed[i] += PadWithSpace(LexAndStem(strValue))
+      }
+    }
+    languages := make(map[*Language]bool)
+    search.TextIndexes = make(map[string]*TextIndex, len(fields))
+    textFields := make([]string, 0, len(fields))
+    for field, fieldData := range fields {
+      fmt.Print(" ", field);
+      Weight := 1.0
+      if weight, has := search.TextWeights[field]; has {
+        Weight = weight
+      }
+      textIndex := NewTextIndex(schema.TotalItems, Weight)
+      getValue := GenericAccessor(fieldData)
+      for i := 0; i < schema.TotalItems; i++ {
+        if value, has := getValue(i); has {
+          analyzer := search.TextAnalyzer(field, i)
+          languages[analyzer.Language] = true
+          values := ExtractStringListFromJson(value)
+          terms := make([][]string, len(values))
+          for j, value := range values {
+            terms[j] = analyzer.LexAndStem(value)
           }
+          textIndex.AddRecord(i, terms)
         }
       }
+      search.TextIndexes[field] = textIndex
+      
+      searchField, has := search.Fields[field]
+      if !has {
+        searchField = SearchField{Entropy: fieldData.Entropy}
+      }
+      searchField.Filters = append(searchField.Filters, "search")
+      searchField.Weight = Weight
+      search.Fields[field] = searchField
+      textFields = append(textFields, field)
+      
+      runtime.GC();
       fmt.Print(",");
     }
-    
-    TextIndexWordCounts := make(map[string]int)
-    for i := 0; i < schema.TotalItems; i++ {
-      str := strings.Split(stemmed[i], " ")
-      out := str[:0]
-      for _, part := range str {
-        if len(part) > 0 && ((part[0] >= '0' && part[0] <= '9') || (part[0] >= 'a' && part[0] <= 'z')) {
-          out = append(out, part)
-          pspace := " " + part + " "
-          TextIndexWordCounts[pspace] = TextIndexWordCounts[pspace] + 1
-        }
-      }
-      stemmed[i] = PadWithSpace(out)
+    for language, _ := range languages {
+      search.TextLanguages = append(search.TextLanguages, language)
     }
     
-    search.TextIndex = Strip(stemmed)
-    search.TextIndexWordCounts = TextIndexWordCounts
-    
-    search.Fields["search"] = SearchField{Filters: []string{"search"}, Entropy: 0}
+    sort.Strings(textFields)
+    search.Fields["search"] = SearchField{Filters: []string{"search"}, Fields: textFields, Entropy: 0}
     fmt.Println(";")
   }
 }
 
+// record language, then field language, then default
+func (search * Search) TextAnalyzer(field string, item int) Analyzer {
+  analyzer := search.Analyzer
+  if language, has := search.FieldLanguages[field]; has {
+    analyzer.Language = language
+  }
+  if search.RecordLanguages != nil && search.RecordLanguages[item] != nil {
+    analyzer.Language = search.RecordLanguages[item]
+  }
+  return analyzer
+}
+
+// per request state shared by filters and output
+type SearchRequest struct {
+  Queries url.Values
+  Errors []string
+  Boosts map[string]float64
+  HighlightTerms HighlightTerms
+  Explain []map[string]interface{}
+  Knn * KnnQuery
+  Query * QueryNode
+  Facets map[string]interface{}
+  Aggs []SearchAggregation // from a search body; the aggs parameter is added to them
+  Aggregations map[string]interface{}
+}
+
+func (search * Search) NewRequest(queries url.Values) * SearchRequest {
+  boosts, errors := ParseBoosts(queries["boost"], search)
+  knn, knnErrors := ParseKnnQuery(queries)
+  errors = append(errors, knnErrors...)
+  return &SearchRequest{Queries: queries, Errors: errors, Boosts: boosts, HighlightTerms: make(HighlightTerms), Explain: make([]map[string]interface{}, 0), Knn: knn}
+}
+
 func (search * Search) Search(queries url.Values, schema * Schema) map[string]interface{} {
+  if queries.Get("cursor") != "" {
+    return search.Resume(queries, schema)
+  }
+  return search.Run(search.NewRequest(queries), schema)
+}
+
+func (search * Search) Run(request * SearchRequest, schema * Schema) map[string]interface{} {
   
-  if len(search.TextIndex) > 1000000 {
+  if len(search.TextIndexes) > 0 && schema.TotalItems > 1000000 {
     search.Lock.Lock()
+    defer search.Lock.Unlock()
   }
   
-  results := SearchStart(schema)
-  
-  errors := make([]string, 0, 10)
-  
-  for _, query := range QueryByEntropy(queries, search) {
-    queryValues := queries[query]
-    field, has := schema.Properties[query]
-    if !has && query == "search" {
-      field = SchemaField{Type: "string"}
-      has = true
-    }
-    if has {
-      searchField := search.Fields[query]
-      switch field.Type {
-        case "boolean":
-          for _, value := range queryValues {
-            if i := strings.Index(value, ":"); i != -1 {
-              value = value[i:]
-            }
-            switch value {
-              case "true":
-                results = SearchBoolean(BooleanAccessor(field), results, true)
-                break;
-              case "false":
-                results = SearchBoolean(BooleanAccessor(field), results, false)
-                break;
-              default:
-                errors = append(errors, "field '" + query + "' value '" + value + "' is not supported")
-            }
+  results := search.Match(request, schema)
+  if _, has := request.Queries["facets"]; has {
+    request.Facets = search.Facets(request, schema, results)
+  }
+  if _, has := request.Queries["aggs"]; has || len(request.Aggs) > 0 {
+    request.Aggregations = search.Aggregations(request, schema, results)
+  }
+  return search.Output(request, schema, results)
+}
+
+// records passing every filter and the nearest neighbour query
+func (search * Search) Match(request * SearchRequest, schema * Schema) SearchResults {
+  results := search.Filter(request, schema, SearchStart(schema))
+  if request.Knn != nil {
+    var errors []string
+    results, errors = search.SearchVector(request.Knn, schema, results)
+    request.Errors = append(request.Errors, errors...)
+  }
+  return results
+}
+
+// applies every field query, lowest entropy first, then q and the body query
+func (search * Search) Filter(request * SearchRequest, schema * Schema, results SearchResults) SearchResults {
+  for _, query := range QueryByEntropy(request.Queries, search) {
+    for _, value := range request.Queries[query] {
+      results = search.FilterField(request, schema, query, value, results)
+    }
+  }
+  if _, has := request.Queries["q"]; has {
+    results = search.FilterQuery(request, schema, results)
+  }
+  if request.Query != nil {
+    results = search.Evaluate(request, schema, request.Query, results)
+  }
+  return results
+}
+
+// value is filter:value or value for the first filter of the field
+func (search * Search) FilterField(request * SearchRequest, schema * Schema, query string, value string, results SearchResults) SearchResults {
+  filter := ""
+  if i := strings.Index(value, ":"); i != -1 {
+    filter = value[:i]
+    value = value[i+1:]
+  }
+  return search.ApplyFilter(request, schema, query, filter, value, results)
+}
+
+// empty filter means the first filter of the field
+func (search * Search) ApplyFilter(request * SearchRequest, schema * Schema, query string, filter string, value string, results SearchResults) SearchResults {
+  field, has := schema.Properties[query]
+  if query == "search" {
+    field = SchemaField{Type: "string"}
+    has = true
+  }
+  if !has {
+    request.Errors = append(request.Errors, "field '" + query + "' does not exist")
+    return results
+  }
+  searchField := search.Fields[query]
+  given := filter
+  if filter == "" && len(searchField.Filters) > 0 {
+    filter = searchField.Filters[0]
+  }
+  if filter == "exists" || filter == "missing" {
+    if field.ValueIndex == nil {
+      request.Errors = append(request.Errors, "field '" + query + "' filter '" + filter + "' is not supported")
+      return results
+    }
+    return SearchExists(field.ValueIndex, results, filter == "exists")
+  }
+  switch field.Type {
+    case "boolean":
+      switch value {
+        case "true":
+          results = SearchBoolean(BooleanAccessor(field), results, true)
+          break;
+        case "false":
+          results = SearchBoolean(BooleanAccessor(field), results, false)
+          break;
+        default:
+          request.Errors = append(request.Errors, "field '" + query + "' value '" + value + "' is not supported")
+      }
+      break
+    case "number":
+      switch filter {
+        case "between":
+          if numberRange, err := ParseNumberRange(value); err == nil {
+            results = SearchNumberBetween(NumberAccessor(field), results, numberRange)
+          } else {
+            request.Errors = append(request.Errors, "field '" + query + "' " + err.Error())
           }
-          break
-        case "number":
-          for _, value := range queryValues {
-            filter := searchField.Filters[0]
-            if i := strings.Index(value, ":"); i != -1 {
-              filter = value[:i]
-              value = value[i+1:]
-            }
-            val, _ := strconv.ParseFloat(value, 64)
-            switch filter {
-              case "equals":
-                results = SearchNumberEqualTo(NumberAccessor(field), results, val)
-                break;
-              case "notEqual":
-                results = SearchNumberNotEqualTo(NumberAccessor(field), results, val)
-                break;
-              case "lessThan":
-                results = SearchNumberLessThan(NumberAccessor(field), results, val)
-                break;
-              case "greaterThan":
-                results = SearchNumberGreaterThan(NumberAccessor(field), results, val)
-                break;
-              default:
-               errors = append(errors, "field '" + query + "' filter '" + filter + "' value '" + value + "' is not supported")
-            }
+          break;
+        case "in":
+          if values, err := ParseNumberList(value); err == nil {
+            results = SearchNumberIn(NumberAccessor(field), results, values)
+          } else {
+            request.Errors = append(request.Errors, "field '" + query + "' " + err.Error())
           }
-          break
-        case "string":
-          for _, value := range queryValues {
-            filter := searchField.Filters[0]
-            if i := strings.Index(value, ":"); i != -1 {
-              filter = value[:i]
-              value = value[i+1:]
-            }
-            switch filter {
-              case "within":
-                results = SearchStringWithin(StringAccessor(field), results, value)
-                break;
-              case "regex":
-                results = SearchStringRegex(StringAccessor(field), results, value)
-                break;
-              case "search":
-                results = SearchStringSearch(StringIndexAccessor(search), search, results, value)
-                break;
-              default:
-                errors = append(errors, "field '" + query + "' filter '" + filter + "' value '" + value + "' is not supported")
-            }
+          break;
+        case "equals", "notEqual", "notEquals", "lessThan", "lessThanOrEqual", "greaterThan", "greaterThanOrEqual":
+          val, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+          if err != nil {
+            request.Errors = append(request.Errors, "field '" + query + "' value '" + value + "' is not a number")
+            break;
           }
-          break
+          switch filter {
+            case "equals":
+              results = SearchNumberEqualTo(NumberAccessor(field), results, val)
+              break;
+            case "notEqual", "notEquals":
+              results = SearchNumberNotEqualTo(NumberAccessor(field), results, val)
+              break;
+            case "lessThan":
+              results = SearchNumberLessThan(NumberAccessor(field), results, val)
+              break;
+            case "lessThanOrEqual":
+              results = SearchNumberLessThanOrEqual(NumberAccessor(field), results, val)
+              break;
+            case "greaterThan":
+              results = SearchNumberGreaterThan(NumberAccessor(field), results, val)
+              break;
+            case "greaterThanOrEqual":
+              results = SearchNumberGreaterThanOrEqual(NumberAccessor(field), results, val)
+              break;
+          }
+          break;
+        default:
+         request.Errors = append(request.Errors, "field '" + query + "' filter '" + filter + "' value '" + value + "' is not supported")
+      }
+      break
+    case "string":
+      switch filter {
+        case "within", "notWithin", "regex":
+          pattern := value
+          if filter != "regex" {
+            pattern = "^(" + value + ")$"
+          }
+          if re, err := regexp.Compile(pattern); err == nil {
+            results = SearchStringRegex(StringAccessor(field), results, re, filter != "notWithin")
+          } else {
+            request.Errors = append(request.Errors, "field '" + query + "' value '" + value + "' is not a regular expression")
+          }
+          break;
+        case "equals", "in", "notIn", "prefix", "suffix", "contains",
+          "equalsIgnoreCase", "inIgnoreCase", "notInIgnoreCase", "prefixIgnoreCase", "suffixIgnoreCase", "containsIgnoreCase":
+          results = SearchStringLiteral(field, results, filter, value)
+          break;
+        case "search":
+          if fields := search.TextFields(query); len(fields) > 0 {
+            results = search.FilterText(request, fields, query, value, results)
+            break;
+          }
+          fallthrough
+        default:
+          request.Errors = append(request.Errors, "field '" + query + "' filter '" + filter + "' value '" + value + "' is not supported")
       }
+      break
+    case "array", "object":
+      // colons belong to the text unless they follow search
+      if given != "" && given != "search" {
+        value = given + ":" + value
+      }
+      if fields := search.TextFields(query); len(fields) > 0 {
+        results = search.FilterText(request, fields, query, value, results)
+      } else {
+        request.Errors = append(request.Errors, "field '" + query + "' value '" + value + "' is not supported")
+      }
+      break
+  }
+  return results
+}
+
+func (search * Search) FilterText(request * SearchRequest, fields []string, query string, value string, results SearchResults) SearchResults {
+  clauses := search.ParseTextQuery(value)
+  request.HighlightTerms.Add(fields, clauses)
+  request.Explain = append(request.Explain, map[string]interface{}{"field": query, "query": value, "clauses": clauses})
+  return SearchStringSearch(search, fields, request.Boosts, results, clauses)
+}
+
+// scores, sorts and pages results
+func (search * Search) Output(request * SearchRequest, schema * Schema, results SearchResults) map[string]interface{} {
+  queries := request.Queries
+  
+  for _, spec := range queries["score"] {
+    if function, err := ParseScoreFunction(spec, schema); err == nil {
+      results = ScoreResults(function, results)
+    } else {
+      request.Errors = append(request.Errors, err.Error())
     }
   }
   
-  sort.Sort(results)
+  sortKeys, err := search.ParseSort(strings.Join(queries["sort"], ","), schema)
+  if err != nil {
+    request.Errors = append(request.Errors, err.Error())
+  }
+  SortResults(sortKeys, schema, results)
+  
+  var collapsed map[int]int
+  if collapse := queries.Get("collapse"); collapse == "duplicates" && len(search.TextIndexes) > 0 {
+    clusters := search.DuplicateIndex(schema).Find(search.DuplicateThreshold)
+    results, collapsed = CollapseDuplicates(ClusterIndex(clusters, schema.TotalItems), results)
+  } else if collapse != "" {
+    request.Errors = append(request.Errors, "collapse '" + collapse + "' is not supported")
+  }
+  
+  if encoder := queries.Get("highlight_encoder"); encoder != "" && encoder != "html" && encoder != "none" {
+    request.Errors = append(request.Errors, "highlight_encoder '" + encoder + "' is not supported")
+  }
+  
+  offset, limit, errors := search.ParsePage(queries)
+  request.Errors = append(request.Errors, errors...)
+  
+  var projection * Projection
+  if fields, has := queries["fields"]; has {
+    if projection, err = ParseProjection(strings.Join(fields, ","), schema); err != nil {
+      request.Errors = append(request.Errors, err.Error())
+    }
+  }
+  
+  cursor := NewCursor(request, results, collapsed, projection, search.CursorWindow)
+  return search.Page(cursor, "", results, offset, limit, request.Errors, schema)
+}
+
+// one page of results, the full set or those kept by the cursor; next and
+// prev cursors are added when there are other pages the cursor keeps, saving
+// it under a new id if it has none
+func (search * Search) Page(cursor * Cursor, id string, results SearchResults, offset int, limit int, errors []string, schema * Schema) map[string]interface{} {
+  request := cursor.Request
+  queries := request.Queries
   
   output := map[string]interface{}{
-    "total": len(results),
-    "limit": RESULTS_TO_RETURN,
+    "total": cursor.Total,
+    "offset": offset,
+    "limit": limit,
   }
   
   if len(errors) > 0 {
     output["errors"] = errors
   }
-
-  if offset, has := queries["offset"]; has {
-    off, _ := strconv.ParseInt(offset[0], 10, 64)
-    results = results[off:]
-    output["offset"] = off
-  } else {
-    output["offset"] = 0
+  
+  if queries.Get("explain") == "true" {
+    output["explain"] = request.Explain
+  }
+  
+  if request.Facets != nil {
+    output["facets"] = request.Facets
+  }
+  
+  if request.Aggregations != nil {
+    output["aggregations"] = request.Aggregations
   }
   
-  if len(results) > RESULTS_TO_RETURN {
-    results = results[:RESULTS_TO_RETURN]
+  next, prev := -1, -1
+  if limit > 0 && offset + limit < cursor.Total && cursor.Covers(offset + limit, limit) {
+    next = offset + limit
+  }
+  if limit > 0 && offset > 0 && cursor.Total > 0 {
+    prev = offset - limit
+    if prev < 0 {
+      prev = 0
+    } else if prev > cursor.Total {
+      prev = (cursor.Total - 1) / limit * limit
+    }
+    if !cursor.Covers(prev, limit) {
+      prev = -1
+    }
   }
+  if (next != -1 || prev != -1) && id == "" {
+    id = search.Cursors.Put(cursor)
+  }
+  if next != -1 {
+    output["next"] = EncodeCursor(search.Version, id, next, limit)
+  }
+  if prev != -1 {
+    output["prev"] = EncodeCursor(search.Version, id, prev, limit)
+  }
+  
+  results = PageResults(results, offset, limit)
+  collapsed := cursor.Collapsed
+  
+  highlightTerms := request.HighlightTerms
+  highlight := queries.Get("highlight") == "true"
+  highlightFields := make([]string, 0, len(highlightTerms))
+  if fields, has := queries["highlight_fields"]; has {
+    for _, field := range strings.Split(strings.Join(fields, ","), ",") {
+      if _, has := highlightTerms[field]; has {
+        highlightFields = append(highlightFields, field)
+      }
+    }
+  } else {
+    for field, _ := range highlightTerms {
+      highlightFields = append(highlightFields, field)
+    }
+  }
+  pre := search.HighlightPre
+  if value, has := queries["highlight_pre"]; has {
+    pre = value[0]
+  }
+  post := search.HighlightPost
+  if value, has := queries["highlight_post"]; has {
+    post = value[0]
+  }
+  escape := queries.Get("highlight_encoder") != "none"
   
   resultObjects := make([]map[string]interface{}, len(results))
   for i, x := range results {
-    item := schema.GetSummary(x.Item, 9)
+    item := schema.Project(x.Item, cursor.Projection, true)
     item["id"] = x.Item
     item["_score"] = x.Score
+    if collapsed != nil {
+      item["_duplicates"] = collapsed[x.Item]
+    }
+    if highlight {
+      highlights := make(map[string][]string)
+      for _, field := range highlightFields {
+        if fragments := search.Highlight(schema, x.Item, field, highlightTerms[field], pre, post, escape); len(fragments) > 0 {
+          highlights[field] = fragments
+        }
+      }
+      item["_highlight"] = highlights
+    }
     resultObjects[i] = item;
   }
   
   output["results"] = resultObjects
   
-  if len(search.TextIndex) > 1000000 {
-    search.Lock.Unlock()
-  }
-  
   return output
 }
 
@@ -310,11 +716,16 @@ func QueryByEntropy(queries url.Values, search * Search) []string {
   return qq
 }
 
-func BooleanAccessor (field SchemaField) func(int) bool {
+func BooleanAccessor (field SchemaField) func(int) (bool, bool) {
   uniqueValues := field.UniqueValues
   valueIndex := field.ValueIndex
-  return func(x int) bool {
-    return uniqueValues[valueIndex[x]].(bool)
+  return func(x int) (bool, bool) {
+    xx := valueIndex[x]
+    if xx != -1 {
+      return uniqueValues[xx].(bool), true
+    } else {
+      return false, false
+    }
   }
 }
 
@@ -357,14 +768,6 @@ func GenericAccessor (field SchemaField) func(int) (interface{}, bool) {
   }
 }
 
-func StringIndexAccessor (schema *Search) func(int) (string, bool) {
-  data := schema.TextIndex
-  return func(x int) (string, bool) {
-    xx := data[x]
-    return xx, len(xx) > 2
-  }
-}
-
 // search methods
 
 type SearchResult struct {
@@ -398,11 +801,22 @@ func SearchStart (schema * Schema) SearchResults {
 }
 
 // boolean search
-func SearchBoolean (accessor func(int) bool, input SearchResults, value bool) SearchResults {
+func SearchBoolean (accessor func(int) (bool, bool), input SearchResults, value bool) SearchResults {
   output := input[:0]
   for _, x := range input {
-    xValue := accessor(x.Item)
-    if xValue == value {
+    xValue, hasValue := accessor(x.Item)
+    if hasValue && xValue == value {
+      output = append(output, x)
+    }
+  }
+  return output
+}
+
+// records with (or without) a value, for fields of any type
+func SearchExists (valueIndex []int, input SearchResults, exists bool) SearchResults {
+  output := input[:0]
+  for _, x := range input {
+    if (valueIndex[x.Item] != -1) == exists {
       output = append(output, x)
     }
   }
@@ -432,6 +846,50 @@ func SearchNumberGreaterThan(accessor func(int) (float64, bool), input SearchRes
   return output
 }
 
+func SearchNumberLessThanOrEqual(accessor func(int) (float64, bool), input SearchResults, value float64) SearchResults {
+  output := input[:0]
+  for _, x := range input {
+    xValue, hasValue := accessor(x.Item)
+    if (hasValue && xValue <= value) {
+      output = append(output, x)
+    }
+  }
+  return output
+}
+
+func SearchNumberGreaterThanOrEqual(accessor func(int) (float64, bool), input SearchResults, value float64) SearchResults {
+  output := input[:0]
+  for _, x := range input {
+    xValue, hasValue := accessor(x.Item)
+    if (hasValue && xValue >= value) {
+      output = append(output, x)
+    }
+  }
+  return output
+}
+
+func SearchNumberBetween(accessor func(int) (float64, bool), input SearchResults, value NumberRange) SearchResults {
+  output := input[:0]
+  for _, x := range input {
+    xValue, hasValue := accessor(x.Item)
+    if (hasValue && value.Contains(xValue)) {
+      output = append(output, x)
+    }
+  }
+  return output
+}
+
+func SearchNumberIn(accessor func(int) (float64, bool), input SearchResults, values map[float64]bool) SearchResults {
+  output := input[:0]
+  for _, x := range input {
+    xValue, hasValue := accessor(x.Item)
+    if (hasValue && values[xValue]) {
+      output = append(output, x)
+    }
+  }
+  return output
+}
+
 func SearchNumberEqualTo(accessor func(int) (float64, bool), input SearchResults, value float64) SearchResults {
   output := input[:0]
   for _, x := range input {
@@ -456,103 +914,145 @@ func SearchNumberNotEqualTo(accessor func(int) (float64, bool), input SearchResu
 
 // string search
 
-func SearchStringWithin(accessor func(int) (string, bool), input SearchResults, value string) SearchResults {
-  return SearchStringRegex(accessor, input, "^(" + value + ")$");
+// compares each unique value once, then filters records by dictionary code;
+// in and notIn take comma separated values
+func SearchStringLiteral(field SchemaField, input SearchResults, filter string, value string) SearchResults {
+  ignoreCase := strings.HasSuffix(filter, "IgnoreCase")
+  filter = strings.TrimSuffix(filter, "IgnoreCase")
+  normalise := func(str string) string {
+    if ignoreCase {
+      return strings.ToLower(str)
+    }
+    return str
+  }
+  value = normalise(value)
+  values := make(map[string]bool)
+  for _, item := range strings.Split(value, ",") {
+    values[item] = true
+  }
+
+  matches := make([]bool, len(field.UniqueValues))
+  for code, unique := range field.UniqueValues {
+    str := normalise(unique.(string))
+    switch filter {
+      case "equals":
+        matches[code] = str == value
+        break;
+      case "in":
+        matches[code] = values[str]
+        break;
+      case "notIn":
+        matches[code] = !values[str]
+        break;
+      case "prefix":
+        matches[code] = strings.HasPrefix(str, value)
+        break;
+      case "suffix":
+        matches[code] = strings.HasSuffix(str, value)
+        break;
+      case "contains":
+        matches[code] = strings.Contains(str, value)
+        break;
+    }
+  }
+
+  valueIndex := field.ValueIndex
+  output := input[:0]
+  for _, x := range input {
+    if code := valueIndex[x.Item]; code != -1 && matches[code] {
+      output = append(output, x)
+    }
+  }
+  return output
 }
 
-func SearchStringRegex(accessor func(int) (string, bool), input SearchResults, value string) SearchResults {
-  re := regexp.MustCompile(value)
+// within matches the whole value: ^(value)$; notWithin keeps the values
+// that do not match
+func SearchStringRegex(accessor func(int) (string, bool), input SearchResults, re * regexp.Regexp, match bool) SearchResults {
   output := input[:0]
   for _, x := range input {
     xValue, hasValue := accessor(x.Item)
-    if hasValue && re.MatchString(xValue) {
+    if hasValue && re.MatchString(xValue) == match {
       output = append(output, x)
     }
   }
   return output
 }
 
-func SearchStringSearch(accessor func(int) (string, bool), search * Search, input SearchResults, value string) SearchResults {
-  
-  tokens := LexAndStem(value)
+func SearchStringSearch(search * Search, fields []string, boosts map[string]float64, input SearchResults, clauses []TextClause) SearchResults {
   
-  present := make([]string, 0, len(tokens))
-  direct := make([]string, 0, len(tokens))
-  absent := make([]string, 0, len(tokens))
-  
-  for i := 0; i < len(tokens); i++ {
-    class := CharClass(rune(tokens[i][0]))
-    if class == '"' {
-      i++
-      slice := " "
-      for tokens[i] != "\"" {
-        slice += tokens[i] + " "
-        present = append(present, tokens[i])
-        i++
-      }
-      direct = append(direct, slice)
-    } else if class == '-' {
-      i++
-      if i < len(tokens) {
-        absent = append(absent, " " + tokens[i] + " ")
+  textIndexes := make([]*TextIndex, len(fields))
+  weights := make([]float64, len(fields))
+  // term codes of every clause alternative, per field
+  codes := make([][][][]int32, len(fields))
+  for i, field := range fields {
+    textIndexes[i] = search.TextIndexes[field]
+    weights[i] = textIndexes[i].Weight
+    if boost, has := boosts[field]; has {
+      weights[i] = boost
+    }
+    codes[i] = make([][][]int32, len(clauses))
+    for c, clause := range clauses {
+      for _, alternative := range clause.Alternatives {
+        if alternativeCodes := textIndexes[i].Codes(alternative); alternativeCodes != nil {
+          codes[i][c] = append(codes[i][c], alternativeCodes)
+        }
       }
-    } else if class == 'a' || class == '0' {
-      present = append(present, " " + tokens[i] + " ")
     }
   }
   
   output := input[:0]
   for _, x := range input {
-    xValue, hasValue := accessor(x.Item)
     
     score := 0.0
+    multiplier := 1.0
+    valid := true
     
-    if hasValue {
-        
-      valid := true
-      
-      if valid {
-        for _, p := range present {
-          count := strings.Count(xValue, p)
-          if count == 0 {
-            valid = false
-            break
-          } else {
-            score += float64(count) / float64(strings.Count(xValue, " ") - 1) / float64(search.TextIndexWordCounts[p])
-          }
+    for c, clause := range clauses {
+      // weights only change the score; matched decides whether it matches
+      count, matched := 0.0, 0.0
+      for i, textIndex := range textIndexes {
+        record := textIndex.Records[x.Item]
+        if len(record) == 0 || len(codes[i][c]) == 0 {
+          continue
         }
-      }
-      
-      if valid {
-        for _, p := range direct {
-          count := strings.Count(xValue, p)
-          if count == 0 {
-            valid = false
-            break
-          } else {
-            score *= float64(count)
+        fieldCount, code := textIndex.Match(record, codes[i][c], clause.Slop)
+        if fieldCount > 0 && clause.Exclude {
+          valid = false
+        } else if fieldCount > 0 {
+          matched += fieldCount
+          count += weights[i] * fieldCount
+          if !clause.Phrase {
+            score += weights[i] * fieldCount / float64(textIndex.Length(record)) / float64(textIndex.WordCounts[code])
           }
         }
       }
-      
-      if valid {
-        for _, p := range absent {
-          if strings.Contains(xValue, p) {
-            valid = false
-            break
-          }
-        }
+      if !clause.Exclude && matched == 0 {
+        valid = false
+      }
+      if clause.Phrase {
+        multiplier *= count
       }
-      
       if !valid {
-        hasValue = false
+        break
       }
     }
-
-    if hasValue {
-      x.Score = score
+    
+    if valid {
+      x.Score = score * multiplier
       output = append(output, x)
     }
   }
   return output
 }
+
+// text indexes searched by a query field; search covers them all
+func (search * Search) TextFields(query string) []string {
+  if query == "search" {
+    return search.Fields["search"].Fields
+  }
+  if _, has := search.TextIndexes[query]; has {
+    return []string{query}
+  }
+  return nil
+}
@@ -0,0 +1,187 @@
+package index
+
+import (
+  "encoding/json"
+  "net/url"
+  "reflect"
+  "sort"
+  "testing"
+)
+
+func TestStringFilters(t *testing.T) {
+  collection := FixtureCollection()
+  tests := []struct {
+    queries url.Values
+    total int
+    err string
+  }{
+    {url.Values{"color": {"red"}}, 134, ""},
+    {url.Values{"color": {"within:red|blue"}}, 267, ""},
+    {url.Values{"color": {"notWithin:red"}}, 266, ""},
+    // notWithin is within negated
+    {url.Values{"color": {"notWithin:red|blue"}}, 133, ""},
+    {url.Values{"color": {"notWithin:re.*"}}, 266, ""},
+    {url.Values{"color": {"notWithin:.*"}}, 0, ""},
+    {url.Values{"color": {"notWithin:red,blue"}}, 400, ""},
+    {url.Values{"color": {"within:red|blue", "notWithin:blue"}}, 134, ""},
+    {url.Values{"color": {"notWithin:("}}, 400, "field 'color' value '(' is not a regular expression"},
+    {url.Values{"color": {"red", "blue"}}, 0, ""},
+    {url.Values{"price": {"lessThan:10"}, "color": {"notWithin:red"}, "brand": {"notWithin:acme"}}, 33, ""},
+    {url.Values{"color": {"within:("}}, 400, "field 'color' value '(' is not a regular expression"},
+    {url.Values{"title": {"regex:^Tele"}}, 134, ""},
+    {url.Values{"title": {"regex:["}}, 400, "field 'title' value '[' is not a regular expression"},
+    {url.Values{"brand": {"inIgnoreCase:ACME,Globex"}}, 200, ""},
+    {url.Values{"brand": {"prefix:a"}}, 100, ""},
+    {url.Values{"color": {"notIn:red"}}, 266, ""},
+  }
+  for _, test := range tests {
+    output := collection.search.Search(test.queries, collection.schema)
+    err := ""
+    if errors, has := output["errors"]; has {
+      err = errors.([]string)[0]
+    }
+    if output["total"] != test.total || err != test.err {
+      t.Errorf("%v: total = %v, errors = %v, want %d, %q", test.queries, output["total"], output["errors"], test.total, test.err)
+    }
+  }
+}
+
+// the filter name before the value is optional
+func TestBooleanFilters(t *testing.T) {
+  collection := FixtureCollection()
+  tests := []struct {
+    queries url.Values
+    total int
+    err string
+  }{
+    {url.Values{"flag": {"true"}}, 200, ""},
+    {url.Values{"flag": {"equals:true"}}, 200, ""},
+    {url.Values{"flag": {"equals:false"}, "color": {"red"}}, 67, ""},
+    {url.Values{"flag": {"true", "false"}}, 0, ""},
+    {url.Values{"flag": {"yes"}}, 400, "field 'flag' value 'yes' is not supported"},
+    {url.Values{"flag": {"equals:yes"}}, 400, "field 'flag' value 'yes' is not supported"},
+  }
+  for _, test := range tests {
+    output := collection.search.Search(test.queries, collection.schema)
+    err := ""
+    if errors, has := output["errors"]; has {
+      err = errors.([]string)[0]
+    }
+    if output["total"] != test.total || err != test.err {
+      t.Errorf("%v: total = %v, errors = %v, want %d, %q", test.queries, output["total"], output["errors"], test.total, test.err)
+    }
+  }
+}
+
+// fields named like query parameters are left out of search, so the
+// parameters keep their meaning
+func TestFilterValues(t *testing.T) {
+  search := FixtureCollection().search
+  for _, field := range search.Fields {
+    for _, filter := range field.Filters {
+      if search.FilterValues[filter] == "" {
+        t.Errorf("filter '%s' is not in filterValues", filter)
+      }
+    }
+  }
+  if want := "comma separated values, ignoring case"; search.FilterValues["inIgnoreCase"] != want {
+    t.Errorf("inIgnoreCase = %q, want %q", search.FilterValues["inIgnoreCase"], want)
+  }
+}
+
+func TestReservedParameters(t *testing.T) {
+  collection := Index(map[string][]interface{}{
+    "sort": {"b", "a", "c"},
+    "limit": {1.0, 2.0, 3.0},
+    "name": {"x", "z", "y"},
+  }, Options{FoldCase: true})
+  collection.Wait()
+  for _, field := range []string{"sort", "limit"} {
+    if _, has := collection.search.Fields[field]; has || collection.search.Searchable(field) {
+      t.Errorf("field '%s' is searchable", field)
+    }
+  }
+  if !collection.search.Searchable("name") {
+    t.Error("field 'name' is not searchable")
+  }
+  output := collection.search.Search(url.Values{"sort": {"-name"}, "limit": {"2"}}, collection.schema)
+  results := output["results"].([]map[string]interface{})
+  if output["errors"] != nil || output["total"] != 3 || len(results) != 2 || results[0]["id"] != 1 || results[1]["id"] != 2 {
+    t.Errorf("sort=-name&limit=2 = %v", output)
+  }
+}
+
+// empty strings are missing, empty arrays and objects are values
+func TestNullFilters(t *testing.T) {
+  collection := Index(map[string][]interface{}{
+    "n": {1.0, nil, 3.0, nil, 0.0},
+    "s": {"a", nil, "", "b", nil},
+    "arr": {[]interface{}{"x"}, []interface{}{}, nil, []interface{}{"y", "z"}, nil},
+    "obj": {map[string]interface{}{"k": "v"}, nil, map[string]interface{}{}, nil, map[string]interface{}{"k": "w"}},
+  }, Options{})
+  collection.Wait()
+  tests := []struct {
+    field string
+    exists []int
+    missing []int
+  }{
+    {"n", []int{0, 2, 4}, []int{1, 3}},
+    {"s", []int{0, 3}, []int{1, 2, 4}},
+    {"arr", []int{0, 1, 3}, []int{2, 4}},
+    {"obj", []int{0, 2, 4}, []int{1, 3}},
+  }
+  for _, test := range tests {
+    for _, filter := range []string{"exists", "missing"} {
+      want := test.exists
+      if filter == "missing" {
+        want = test.missing
+      }
+      // the value is ignored
+      for _, value := range []string{filter + ":", filter + ":true"} {
+        output := collection.search.Search(url.Values{test.field: {value}}, collection.schema)
+        ids := make([]int, 0)
+        for _, result := range output["results"].([]map[string]interface{}) {
+          ids = append(ids, result["id"].(int))
+        }
+        sort.Ints(ids)
+        if !reflect.DeepEqual(ids, want) || output["errors"] != nil {
+          t.Errorf("%s=%s: %v, errors %v, want %v", test.field, value, ids, output["errors"], want)
+        }
+      }
+    }
+    searchField := collection.search.Fields[test.field]
+    if filters := searchField.Filters; len(filters) < 2 || filters[len(filters) - 2] != "exists" || filters[len(filters) - 1] != "missing" {
+      t.Errorf("%s filters = %v", test.field, filters)
+    }
+    if searchField.NullCount != len(test.missing) {
+      t.Errorf("%s nullCount = %d, want %d", test.field, searchField.NullCount, len(test.missing))
+    }
+  }
+}
+
+func TestNullCount(t *testing.T) {
+  collection := FixtureCollection()
+  encoded, err := json.Marshal(collection.SearchMeta())
+  if err != nil {
+    t.Fatal(err)
+  }
+  var meta struct {
+    Fields map[string]struct {
+      NullCount int `json:"nullCount"`
+    } `json:"fields"`
+  }
+  if err := json.Unmarshal(encoded, &meta); err != nil {
+    t.Fatal(err)
+  }
+  // price is missing from every seventh record
+  want := map[string]int{"title": 0, "color": 0, "brand": 0, "price": 58, "popularity": 0, "flag": 0}
+  for field, nullCount := range want {
+    if got := meta.Fields[field].NullCount; got != nullCount {
+      t.Errorf("%s nullCount = %d, want %d", field, got, nullCount)
+    }
+  }
+  total := collection.search.Search(url.Values{"price": {"missing:true"}}, collection.schema)["total"]
+  if total != 58 {
+    t.Errorf("price=missing:true total = %v, want 58", total)
+  }
+}
@@ -0,0 +1,166 @@
+package index
+
+import (
+  "math"
+  "net/url"
+  "sort"
+)
+
+const SIMILAR_TERMS int = 25
+
+const BM25_K1 float64 = 1.2
+const BM25_B float64 = 0.75
+
+// a term of the source record used to find similar records
+type SimilarTerm struct {
+  Field string `json:"field"`
+  Term string `json:"term"`
+  Weight float64 `json:"weight"`
+  FieldIndex int `json:"-"`
+  Code int32 `json:"-"`
+}
+
+type SimilarTerms []SimilarTerm
+
+func (this SimilarTerms) Len() int {
+  return len(this)
+}
+
+func (this SimilarTerms) Less(i, j int) bool {
+  if this[i].Weight == this[j].Weight {
+    return this[i].Term < this[j].Term
+  }
+  return this[i].Weight > this[j].Weight
+}
+
+func (this SimilarTerms) Swap(i, j int) {
+  t := this[i]
+  this[i] = this[j]
+  this[j] = t
+}
+
+func (textIndex * TextIndex) IDF(code int32) float64 {
+  documents := float64(textIndex.Documents[code])
+  return math.Log(1 + (float64(textIndex.RecordCount) - documents + 0.5) / (documents + 0.5))
+}
+
+// records most similar to item by full text content; other queries filter
+func (search * Search) Similar(item int, queries url.Values, schema * Schema) map[string]interface{} {
+  if queries.Get("cursor") != "" {
+    return search.Resume(queries, schema)
+  }
+
+  if len(search.TextIndexes) > 0 && schema.TotalItems > 1000000 {
+    search.Lock.Lock()
+    defer search.Lock.Unlock()
+  }
+
+  request := search.NewRequest(queries)
+  fields := search.TextFields("search")
+  if len(fields) == 0 {
+    request.Errors = append(request.Errors, "collection has no full text fields")
+    return search.Output(request, schema, SearchResults{})
+  }
+
+  results := search.Filter(request, schema, SearchStart(schema))
+  terms := search.SimilarTerms(fields, item, request.Boosts)
+
+  for _, term := range terms {
+    if request.HighlightTerms[term.Field] == nil {
+      request.HighlightTerms[term.Field] = make(map[string]bool)
+    }
+    request.HighlightTerms[term.Field][term.Term] = true
+  }
+  request.Explain = append(request.Explain, map[string]interface{}{"similar": item, "terms": terms})
+
+  return search.Output(request, schema, SearchSimilar(search, fields, request.Boosts, item, results, terms))
+}
+
+// highest tf-idf terms of item across fields
+func (search * Search) SimilarTerms(fields []string, item int, boosts map[string]float64) SimilarTerms {
+  terms := make(SimilarTerms, 0, SIMILAR_TERMS)
+  for i, field := range fields {
+    textIndex := search.TextIndexes[field]
+    weight := textIndex.Weight
+    if boost, has := boosts[field]; has {
+      weight = boost
+    }
+    counts := make(map[int32]int)
+    for _, code := range textIndex.Records[item] {
+      if code != TEXT_GAP {
+        counts[code]++
+      }
+    }
+    for code, count := range counts {
+      // terms found in a single record can not match anything else
+      if textIndex.Documents[code] > 1 {
+        terms = append(terms, SimilarTerm{Field: field, Term: textIndex.TermList[code], Weight: weight * float64(count) * textIndex.IDF(code), FieldIndex: i, Code: code})
+      }
+    }
+  }
+  sort.Sort(terms)
+  if len(terms) > SIMILAR_TERMS {
+    terms = terms[:SIMILAR_TERMS]
+  }
+  return terms
+}
+
+// bm25 score of each record against the terms; item itself is dropped
+func SearchSimilar(search * Search, fields []string, boosts map[string]float64, item int, input SearchResults, terms SimilarTerms) SearchResults {
+
+  textIndexes := make([]*TextIndex, len(fields))
+  weights := make([]float64, len(fields))
+  averages := make([]float64, len(fields))
+  // term position by code, per field
+  lookup := make([]map[int32]int, len(fields))
+  for i, field := range fields {
+    textIndexes[i] = search.TextIndexes[field]
+    weights[i] = textIndexes[i].Weight
+    if boost, has := boosts[field]; has {
+      weights[i] = boost
+    }
+    if textIndexes[i].RecordCount > 0 {
+      averages[i] = float64(textIndexes[i].TotalLength) / float64(textIndexes[i].RecordCount)
+    }
+    lookup[i] = make(map[int32]int)
+  }
+  idf := make([]float64, len(terms))
+  for t, term := range terms {
+    lookup[term.FieldIndex][term.Code] = t
+    idf[t] = textIndexes[term.FieldIndex].IDF(term.Code)
+  }
+
+  counts := make([]float64, len(terms))
+  output := input[:0]
+  for _, x := range input {
+    if x.Item == item {
+      continue
+    }
+    score := 0.0
+    for i, textIndex := range textIndexes {
+      record := textIndex.Records[x.Item]
+      if len(record) == 0 || len(lookup[i]) == 0 {
+        continue
+      }
+      for t := range counts {
+        counts[t] = 0
+      }
+      for _, code := range record {
+        if t, has := lookup[i][code]; has {
+          counts[t]++
+        }
+      }
+      norm := BM25_K1 * (1 - BM25_B + BM25_B * float64(textIndex.Length(record)) / averages[i])
+      for t, count := range counts {
+        if count > 0 {
+          score += weights[i] * idf[t] * count * (BM25_K1 + 1) / (count + norm)
+        }
+      }
+    }
+    if score > 0 {
+      x.Score = score
+      output = append(output, x)
+    }
+  }
+  return output
+}
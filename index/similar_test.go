@@ -0,0 +1,105 @@
+package index
+
+import (
+  "math"
+  "net/url"
+  "reflect"
+  "testing"
+)
+
+func SimilarCollection() Collection {
+  collection := Index(map[string][]interface{}{
+    "body": {
+      []interface{}{"apple banana cherry"},
+      []interface{}{"apple banana"},
+      []interface{}{"apple durian"},
+      []interface{}{"banana cherry cherry"},
+      []interface{}{"elder fig"},
+    },
+    "n": {0.0, 1.0, 2.0, 3.0, 4.0},
+  }, Options{Language: "none"})
+  collection.Wait()
+  return collection
+}
+
+// idf of a term in d of the 5 records
+func SimilarIDF(d float64) float64 {
+  return math.Log(1 + (5 - d + 0.5) / (d + 0.5))
+}
+
+func TestSimilarTerms(t *testing.T) {
+  collection := SimilarCollection()
+  tests := []struct {
+    item int
+    boosts map[string]float64
+    want []string
+    weights []float64
+  }{
+    // terms of no other record are left out
+    {0, nil, []string{"cherry", "apple", "banana"}, []float64{SimilarIDF(2), SimilarIDF(3), SimilarIDF(3)}},
+    {3, nil, []string{"cherry", "banana"}, []float64{2 * SimilarIDF(2), SimilarIDF(3)}},
+    {3, map[string]float64{"body": 0.5}, []string{"cherry", "banana"}, []float64{SimilarIDF(2), 0.5 * SimilarIDF(3)}},
+    {4, nil, []string{}, []float64{}},
+  }
+  for _, test := range tests {
+    terms := collection.search.SimilarTerms([]string{"body"}, test.item, test.boosts)
+    got := make([]string, len(terms))
+    for i, term := range terms {
+      got[i] = term.Term
+      if term.Field != "body" || math.Abs(term.Weight - test.weights[i]) > 1e-12 {
+        t.Errorf("SimilarTerms(%d) term %d = %+v, want weight %v", test.item, i, term, test.weights[i])
+      }
+    }
+    if !reflect.DeepEqual(got, test.want) {
+      t.Errorf("SimilarTerms(%d) = %q, want %q", test.item, got, test.want)
+    }
+  }
+}
+
+// bm25 with k1 1.2 and b 0.75; the average record has 12 / 5 terms
+func TestSimilarScores(t *testing.T) {
+  collection := SimilarCollection()
+  bm25 := func(idf float64, count float64, length float64) float64 {
+    return idf * count * (BM25_K1 + 1) / (count + BM25_K1 * (1 - BM25_B + BM25_B * length / 2.4))
+  }
+  tests := []struct {
+    queries url.Values
+    ids []int
+    scores []float64
+  }{
+    {url.Values{}, []int{3, 1, 2}, []float64{
+      bm25(SimilarIDF(3), 1, 3) + bm25(SimilarIDF(2), 2, 3),
+      2 * bm25(SimilarIDF(3), 1, 2),
+      bm25(SimilarIDF(3), 1, 2),
+    }},
+    // other queries filter
+    {url.Values{"n": {"lessThan:3"}}, []int{1, 2}, []float64{2 * bm25(SimilarIDF(3), 1, 2), bm25(SimilarIDF(3), 1, 2)}},
+    {url.Values{"limit": {"1"}, "offset": {"1"}}, []int{1}, []float64{2 * bm25(SimilarIDF(3), 1, 2)}},
+  }
+  for _, test := range tests {
+    output := collection.search.Similar(0, test.queries, collection.schema)
+    if output["errors"] != nil {
+      t.Errorf("Similar(0, %v) errors = %v", test.queries, output["errors"])
+    }
+    results := output["results"].([]map[string]interface{})
+    if len(results) != len(test.ids) {
+      t.Errorf("Similar(0, %v) = %v, want %v", test.queries, results, test.ids)
+      continue
+    }
+    for i, result := range results {
+      if result["id"] != test.ids[i] || math.Abs(result["_score"].(float64) - test.scores[i]) > 1e-12 {
+        t.Errorf("Similar(0, %v) result %d = %v, want id %d, score %v", test.queries, i, result, test.ids[i], test.scores[i])
+      }
+    }
+  }
+
+  if output := collection.search.Similar(4, url.Values{}, collection.schema); output["total"] != 0 {
+    t.Errorf("Similar(4) total = %v, want 0", output["total"])
+  }
+  collection = Index(map[string][]interface{}{"n": {0.0, 1.0}}, Options{})
+  collection.Wait()
+  output := collection.search.Similar(0, url.Values{}, collection.schema)
+  if errors := output["errors"]; !reflect.DeepEqual(errors, []string{"collection has no full text fields"}) {
+    t.Errorf("errors = %v", errors)
+  }
+}
@@ -0,0 +1,93 @@
+package index
+
+import (
+  "fmt"
+  "sort"
+  "strings"
+)
+
+// sort=price,-rating,name:nullsFirst
+// - reverses a key; _score sorts best first unless reversed. Records
+// without a value sort last unless nullsFirst is given. Ties go to the
+// lower id.
+type SortKey struct {
+  Field string
+  Descending bool
+  NullsFirst bool
+}
+
+// boolean, number and string dictionaries are sorted, so codes compare as
+// values do
+func Sortable(fieldData SchemaField) bool {
+  return fieldData.Type == "boolean" || fieldData.Type == "number" || fieldData.Type == "string"
+}
+
+func (search * Search) ParseSort(value string, schema * Schema) ([]SortKey, error) {
+  keys := make([]SortKey, 0)
+  for _, item := range strings.Split(value, ",") {
+    item = strings.TrimSpace(item)
+    if item == "" {
+      continue
+    }
+    key := SortKey{}
+    if strings.HasPrefix(item, "-") {
+      key.Descending = true
+      item = item[1:]
+    }
+    if i := strings.Index(item, ":"); i != -1 {
+      switch item[i + 1:] {
+        case "nullsFirst":
+          key.NullsFirst = true
+          break;
+        case "nullsLast":
+          break;
+        default:
+          return nil, fmt.Errorf("sort '%s' must be nullsFirst or nullsLast", item[i + 1:])
+      }
+      item = item[:i]
+    }
+    key.Field = item
+    if key.Field == "_score" {
+      key.Descending = !key.Descending
+    } else if fieldData, has := schema.Properties[key.Field]; !has || !search.Searchable(key.Field) {
+      return nil, fmt.Errorf("sort field '%s' does not exist", key.Field)
+    } else if !Sortable(fieldData) {
+      return nil, fmt.Errorf("sort field '%s' is %s and can not be sorted", key.Field, fieldData.Type)
+    }
+    keys = append(keys, key)
+  }
+  return keys, nil
+}
+
+// by score when there are no keys
+func SortResults(keys []SortKey, schema * Schema, results SearchResults) {
+  if len(keys) == 0 {
+    keys = []SortKey{{Field: "_score", Descending: true}}
+  }
+  valueIndexes := make([][]int, len(keys))
+  for k, key := range keys {
+    if key.Field != "_score" {
+      valueIndexes[k] = schema.Properties[key.Field].ValueIndex
+    }
+  }
+  sort.Slice(results, func(i, j int) bool {
+    a, b := results[i], results[j]
+    for k, key := range keys {
+      if valueIndexes[k] == nil {
+        if a.Score != b.Score {
+          return (a.Score < b.Score) != key.Descending
+        }
+        continue
+      }
+      x, y := valueIndexes[k][a.Item], valueIndexes[k][b.Item]
+      if x == y {
+        continue
+      }
+      if x == -1 || y == -1 {
+        return (x == -1) == key.NullsFirst
+      }
+      return (x < y) != key.Descending
+    }
+    return a.Item < b.Item
+  })
+}
@@ -0,0 +1,61 @@
+package index
+
+import (
+  "net/url"
+  "reflect"
+  "testing"
+)
+
+func TestParseSort(t *testing.T) {
+  collection := FixtureCollection()
+  tests := []struct {
+    value string
+    want []SortKey
+    err string
+  }{
+    {"price", []SortKey{{Field: "price"}}, ""},
+    {"-price:nullsFirst, brand", []SortKey{{Field: "price", Descending: true, NullsFirst: true}, {Field: "brand"}}, ""},
+    {"price:nullsLast", []SortKey{{Field: "price"}}, ""},
+    {"_score", []SortKey{{Field: "_score", Descending: true}}, ""},
+    {"-_score", []SortKey{{Field: "_score"}}, ""},
+    {"", []SortKey{}, ""},
+    {"nope", nil, "sort field 'nope' does not exist"},
+    {"price:desc", nil, "sort 'desc' must be nullsFirst or nullsLast"},
+  }
+  for _, test := range tests {
+    keys, err := collection.search.ParseSort(test.value, collection.schema)
+    if err != nil {
+      if err.Error() != test.err {
+        t.Errorf("ParseSort(%q) error = %v, want %q", test.value, err, test.err)
+      }
+    } else if test.err != "" || !reflect.DeepEqual(keys, test.want) {
+      t.Errorf("ParseSort(%q) = %+v, want %+v, error %q", test.value, keys, test.want, test.err)
+    }
+  }
+}
+
+func TestSortResults(t *testing.T) {
+  collection := FixtureCollection()
+  // price is the record id modulo 50, missing every seventh record
+  tests := []struct {
+    sort string
+    ids []int
+  }{
+    {"price", []int{50, 100, 150, 200, 250}},
+    {"-price", []int{99, 149, 199, 249, 299}},
+    {"price:nullsFirst", []int{0, 7, 14, 21, 28}},
+    {"-price:nullsFirst,-popularity", []int{399, 392, 385, 378, 371}},
+    {"color,-popularity", []int{397, 394, 391, 388, 385}},
+    {"-flag,price", []int{50, 100, 150, 200, 250}},
+  }
+  for _, test := range tests {
+    output := collection.search.Search(url.Values{"sort": {test.sort}, "limit": {"5"}}, collection.schema)
+    ids := make([]int, 0, 5)
+    for _, result := range output["results"].([]map[string]interface{}) {
+      ids = append(ids, result["id"].(int))
+    }
+    if !reflect.DeepEqual(ids, test.ids) || output["errors"] != nil {
+      t.Errorf("sort=%s: ids = %v, errors = %v, want %v", test.sort, ids, output["errors"], test.ids)
+    }
+  }
+}
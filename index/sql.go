@@ -0,0 +1,845 @@
+package index
+
+import (
+  "fmt"
+  "net/url"
+  "regexp"
+  "sort"
+  "strconv"
+  "strings"
+  "unicode"
+  "unicode/utf8"
+)
+
+// read only subset:
+// SELECT columns FROM name [WHERE ...] [GROUP BY ...] [ORDER BY ...] [LIMIT n] [OFFSET n]
+
+// the one table; named like the elasticsearch index and the odata entity set
+const SQL_TABLE string = "items"
+
+var SQL_KEYWORDS = map[string]bool{
+  "SELECT": true, "FROM": true, "WHERE": true, "GROUP": true, "BY": true, "ORDER": true,
+  "ASC": true, "DESC": true, "LIMIT": true, "OFFSET": true, "AND": true, "OR": true,
+  "NOT": true, "IN": true, "BETWEEN": true, "LIKE": true, "IS": true, "NULL": true,
+  "AS": true, "TRUE": true, "FALSE": true,
+}
+
+var SQL_COMPARISONS = map[string]bool{"=": true, "!=": true, "<>": true, "<": true, "<=": true, ">": true, ">=": true}
+
+type SQLToken struct {
+  Kind string // keyword, identifier, number, string, symbol, end
+  Text string
+  Position int
+}
+
+// output column; a field or an aggregate
+type SQLColumn struct {
+  Name string
+  Field string
+  Function string
+}
+
+type SQLOrder struct {
+  Column SQLColumn
+  Position int // 1 based select column, 0 when named
+  Descending bool
+}
+
+type SQLQuery struct {
+  Columns []SQLColumn
+  Star bool
+  From string
+  Where * QueryNode
+  GroupBy []string
+  OrderBy []SQLOrder
+  Limit int // -1 for all rows
+  Offset int
+}
+
+type SQLParser struct {
+  Statement string
+  Tokens []SQLToken
+  Next int
+  Search * Search
+  Schema * Schema
+}
+
+func TokenizeSQL(statement string) ([]SQLToken, error) {
+  tokens := make([]SQLToken, 0)
+  for i := 0; i < len(statement); {
+    item, size := utf8.DecodeRuneInString(statement[i:])
+    start := i
+    switch {
+      case unicode.IsSpace(item):
+        i += size
+        continue
+      case item == '\'':
+        // '' is an escaped quote
+        text := ""
+        for i++; ; i++ {
+          if i >= len(statement) {
+            return nil, SQLError("unterminated string", statement, start)
+          }
+          if statement[i] == '\'' {
+            if i + 1 < len(statement) && statement[i + 1] == '\'' {
+              text += "'"
+              i++
+              continue
+            }
+            i++
+            break
+          }
+          text += statement[i:i + 1]
+        }
+        tokens = append(tokens, SQLToken{"string", text, start})
+        continue
+      case item == '"' || item == '`':
+        end := strings.IndexRune(statement[i + 1:], item)
+        if end == -1 {
+          return nil, SQLError("unterminated identifier", statement, start)
+        }
+        tokens = append(tokens, SQLToken{"identifier", statement[i + 1:i + 1 + end], start})
+        i += end + 2
+        continue
+      case unicode.IsDigit(item) || (item == '.' && i + 1 < len(statement) && unicode.IsDigit(rune(statement[i + 1]))):
+        for i < len(statement) && (unicode.IsDigit(rune(statement[i])) || strings.ContainsRune(".eE", rune(statement[i])) || ((statement[i] == '-' || statement[i] == '+') && strings.ContainsRune("eE", rune(statement[i - 1])))) {
+          i++
+        }
+        tokens = append(tokens, SQLToken{"number", statement[start:i], start})
+        continue
+      case unicode.IsLetter(item) || item == '_':
+        for i < len(statement) {
+          item, size := utf8.DecodeRuneInString(statement[i:])
+          if !unicode.IsLetter(item) && !unicode.IsDigit(item) && item != '_' && item != '.' {
+            break
+          }
+          i += size
+        }
+        text := statement[start:i]
+        if SQL_KEYWORDS[strings.ToUpper(text)] {
+          tokens = append(tokens, SQLToken{"keyword", strings.ToUpper(text), start})
+        } else {
+          tokens = append(tokens, SQLToken{"identifier", text, start})
+        }
+        continue
+    }
+    for _, symbol := range []string{"<=", ">=", "<>", "!=", "=", "<", ">", "(", ")", ",", "*", "-", ";"} {
+      if strings.HasPrefix(statement[i:], symbol) {
+        tokens = append(tokens, SQLToken{"symbol", symbol, start})
+        i += len(symbol)
+        break
+      }
+    }
+    if i == start {
+      return nil, SQLError("unexpected '" + string(item) + "'", statement, start)
+    }
+  }
+  return append(tokens, SQLToken{"end", "", len(statement)}), nil
+}
+
+func SQLError(message string, statement string, offset int) error {
+  return fmt.Errorf("%s at character %d", message, utf8.RuneCountInString(statement[:offset]) + 1)
+}
+
+func (parser * SQLParser) Peek() SQLToken {
+  return parser.Tokens[parser.Next]
+}
+
+func (parser * SQLParser) Error(message string) error {
+  return SQLError(message, parser.Statement, parser.Peek().Position)
+}
+
+func (parser * SQLParser) Unexpected() error {
+  token := parser.Peek()
+  if token.Kind == "end" {
+    return parser.Error("unexpected end of statement")
+  }
+  return parser.Error("unexpected '" + token.Text + "'")
+}
+
+// consumes the keyword or symbol if it is next
+func (parser * SQLParser) Accept(text string) bool {
+  token := parser.Peek()
+  if (token.Kind == "keyword" || token.Kind == "symbol") && token.Text == text {
+    parser.Next++
+    return true
+  }
+  return false
+}
+
+func (parser * SQLParser) Expect(text string) error {
+  if !parser.Accept(text) {
+    if parser.Peek().Kind == "end" {
+      return parser.Error("expected " + text)
+    }
+    return parser.Error("expected " + text + " instead of '" + parser.Peek().Text + "'")
+  }
+  return nil
+}
+
+func (parser * SQLParser) Identifier() (string, error) {
+  token := parser.Peek()
+  if token.Kind != "identifier" {
+    if token.Kind == "end" {
+      return "", parser.Error("expected a name")
+    }
+    return "", parser.Error("expected a name instead of '" + token.Text + "'")
+  }
+  parser.Next++
+  return token.Text, nil
+}
+
+func (parser * SQLParser) Field() (string, error) {
+  position := parser.Peek().Position
+  field, err := parser.Identifier()
+  if err == nil {
+    if _, has := parser.Schema.Properties[field]; !has {
+      return "", SQLError("unknown field '" + field + "'", parser.Statement, position)
+    }
+  }
+  return field, err
+}
+
+func (search * Search) ParseSQL(statement string, schema * Schema) (* SQLQuery, error) {
+  tokens, err := TokenizeSQL(statement)
+  if err != nil {
+    return nil, err
+  }
+  parser := &SQLParser{Statement: statement, Tokens: tokens, Search: search, Schema: schema}
+  query := &SQLQuery{Limit: -1}
+
+  if err := parser.Expect("SELECT"); err != nil {
+    return nil, err
+  }
+  if parser.Accept("*") {
+    query.Star = true
+  } else {
+    for {
+      column, err := parser.ParseColumn()
+      if err != nil {
+        return nil, err
+      }
+      if parser.Accept("AS") {
+        if column.Name, err = parser.Identifier(); err != nil {
+          return nil, err
+        }
+      }
+      query.Columns = append(query.Columns, column)
+      if !parser.Accept(",") {
+        break
+      }
+    }
+  }
+  if err := parser.Expect("FROM"); err != nil {
+    return nil, err
+  }
+  position := parser.Peek().Position
+  if query.From, err = parser.Identifier(); err != nil {
+    return nil, err
+  }
+  if !strings.EqualFold(query.From, SQL_TABLE) {
+    return nil, SQLError("unknown table '" + query.From + "', expected " + SQL_TABLE, parser.Statement, position)
+  }
+  if parser.Accept("WHERE") {
+    if query.Where, err = parser.ParseOr(); err != nil {
+      return nil, err
+    }
+  }
+  if parser.Accept("GROUP") {
+    if err := parser.Expect("BY"); err != nil {
+      return nil, err
+    }
+    for {
+      field, err := parser.Field()
+      if err != nil {
+        return nil, err
+      }
+      query.GroupBy = append(query.GroupBy, field)
+      if !parser.Accept(",") {
+        break
+      }
+    }
+  }
+  if parser.Accept("ORDER") {
+    if err := parser.Expect("BY"); err != nil {
+      return nil, err
+    }
+    for {
+      order := SQLOrder{}
+      if token := parser.Peek(); token.Kind == "number" {
+        order.Position, _ = strconv.Atoi(token.Text)
+        parser.Next++
+      } else if order.Column, err = parser.ParseColumn(); err != nil {
+        return nil, err
+      }
+      if parser.Accept("DESC") {
+        order.Descending = true
+      } else {
+        parser.Accept("ASC")
+      }
+      query.OrderBy = append(query.OrderBy, order)
+      if !parser.Accept(",") {
+        break
+      }
+    }
+  }
+  if parser.Accept("LIMIT") {
+    if query.Limit, err = parser.Count(); err != nil {
+      return nil, err
+    }
+  }
+  if parser.Accept("OFFSET") {
+    if query.Offset, err = parser.Count(); err != nil {
+      return nil, err
+    }
+  }
+  parser.Accept(";")
+  if parser.Peek().Kind != "end" {
+    return nil, parser.Unexpected()
+  }
+  return query, nil
+}
+
+func (parser * SQLParser) Count() (int, error) {
+  token := parser.Peek()
+  count, err := strconv.Atoi(token.Text)
+  if token.Kind != "number" || err != nil || count < 0 {
+    return 0, parser.Error("expected a whole number")
+  }
+  parser.Next++
+  return count, nil
+}
+
+// field or aggregate(field), aggregate(*)
+func (parser * SQLParser) ParseColumn() (SQLColumn, error) {
+  position := parser.Peek().Position
+  name, err := parser.Identifier()
+  if err != nil {
+    return SQLColumn{}, err
+  }
+  if !parser.Accept("(") {
+    if _, has := parser.Schema.Properties[name]; !has {
+      // may name a select column in ORDER BY
+      return SQLColumn{Name: name}, nil
+    }
+    return SQLColumn{Name: name, Field: name}, nil
+  }
+  aggregate := Aggregate{Function: strings.ToLower(name)}
+  if !parser.Accept("*") {
+    if aggregate.Field, err = parser.Field(); err != nil {
+      return SQLColumn{}, err
+    }
+  }
+  if err := parser.Expect(")"); err != nil {
+    return SQLColumn{}, err
+  }
+  if err := aggregate.Check(parser.Schema); err != nil {
+    return SQLColumn{}, SQLError(err.Error(), parser.Statement, position)
+  }
+  return SQLColumn{Name: aggregate.Name(), Field: aggregate.Field, Function: aggregate.Function}, nil
+}
+
+func (parser * SQLParser) ParseOr() (* QueryNode, error) {
+  node, err := parser.ParseAnd()
+  if err != nil {
+    return nil, err
+  }
+  children := []*QueryNode{node}
+  for parser.Accept("OR") {
+    if node, err = parser.ParseAnd(); err != nil {
+      return nil, err
+    }
+    children = append(children, node)
+  }
+  if len(children) == 1 {
+    return node, nil
+  }
+  return &QueryNode{Operator: "or", Children: children}, nil
+}
+
+func (parser * SQLParser) ParseAnd() (* QueryNode, error) {
+  node, err := parser.ParseNot()
+  if err != nil {
+    return nil, err
+  }
+  children := []*QueryNode{node}
+  for parser.Accept("AND") {
+    if node, err = parser.ParseNot(); err != nil {
+      return nil, err
+    }
+    children = append(children, node)
+  }
+  if len(children) == 1 {
+    return node, nil
+  }
+  return &QueryNode{Operator: "and", Children: children}, nil
+}
+
+func (parser * SQLParser) ParseNot() (* QueryNode, error) {
+  if parser.Accept("NOT") {
+    node, err := parser.ParseNot()
+    if err != nil {
+      return nil, err
+    }
+    return &QueryNode{Operator: "not", Children: []*QueryNode{node}}, nil
+  }
+  if parser.Accept("(") {
+    node, err := parser.ParseOr()
+    if err != nil {
+      return nil, err
+    }
+    return node, parser.Expect(")")
+  }
+  return parser.ParseCondition()
+}
+
+// literal string, number, TRUE, FALSE; NULL is only allowed in IS NULL
+func (parser * SQLParser) Literal() (interface{}, error) {
+  token := parser.Peek()
+  negative := false
+  if token.Kind == "symbol" && token.Text == "-" {
+    negative = true
+    parser.Next++
+    token = parser.Peek()
+    if token.Kind != "number" {
+      return nil, parser.Error("expected a number")
+    }
+  }
+  switch {
+    case token.Kind == "string":
+      parser.Next++
+      return token.Text, nil
+    case token.Kind == "number":
+      number, err := strconv.ParseFloat(token.Text, 64)
+      if err != nil {
+        return nil, parser.Error("invalid number '" + token.Text + "'")
+      }
+      parser.Next++
+      if negative {
+        number = -number
+      }
+      return number, nil
+    case token.Kind == "keyword" && (token.Text == "TRUE" || token.Text == "FALSE"):
+      parser.Next++
+      return token.Text == "TRUE", nil
+  }
+  return nil, parser.Error("expected a value")
+}
+
+// match(field, 'text') or field compared with values
+func (parser * SQLParser) ParseCondition() (* QueryNode, error) {
+  start := parser.Peek()
+  if start.Kind == "identifier" && strings.ToLower(start.Text) == "match" && parser.Tokens[parser.Next + 1].Text == "(" {
+    parser.Next += 2
+    field := "search"
+    if !parser.Accept("*") {
+      position := parser.Peek().Position
+      var err error
+      if field, err = parser.Identifier(); err != nil {
+        return nil, err
+      }
+      if len(parser.Search.TextFields(field)) == 0 {
+        return nil, SQLError("'" + field + "' is not a full text field", parser.Statement, position)
+      }
+    } else if len(parser.Search.TextFields(field)) == 0 {
+      return nil, parser.Error("collection has no full text fields")
+    }
+    if err := parser.Expect(","); err != nil {
+      return nil, err
+    }
+    token := parser.Peek()
+    if token.Kind != "string" {
+      return nil, parser.Error("expected search text")
+    }
+    parser.Next++
+    return &QueryNode{Field: field, Filter: "search", Value: token.Text}, parser.Expect(")")
+  }
+
+  field, err := parser.Field()
+  if err != nil {
+    return nil, err
+  }
+
+  filter := ""
+  negate := false
+  var values []interface{}
+  if parser.Accept("IS") {
+    negate = parser.Accept("NOT")
+    if err := parser.Expect("NULL"); err != nil {
+      return nil, err
+    }
+    filter = "null"
+  } else {
+    negate = parser.Accept("NOT")
+    switch {
+      case parser.Accept("IN"):
+        filter = "in"
+        if err := parser.Expect("("); err != nil {
+          return nil, err
+        }
+        for {
+          value, err := parser.Literal()
+          if err != nil {
+            return nil, err
+          }
+          values = append(values, value)
+          if !parser.Accept(",") {
+            break
+          }
+        }
+        if err := parser.Expect(")"); err != nil {
+          return nil, err
+        }
+        break;
+      case parser.Accept("BETWEEN"):
+        filter = "between"
+        low, err := parser.Literal()
+        if err != nil {
+          return nil, err
+        }
+        if err := parser.Expect("AND"); err != nil {
+          return nil, err
+        }
+        high, err := parser.Literal()
+        if err != nil {
+          return nil, err
+        }
+        values = []interface{}{low, high}
+        break;
+      case parser.Accept("LIKE"):
+        filter = "like"
+        pattern, err := parser.Literal()
+        if err != nil {
+          return nil, err
+        }
+        values = []interface{}{pattern}
+        break;
+      default:
+        if negate {
+          return nil, parser.Error("expected IN, BETWEEN or LIKE after NOT")
+        }
+        token := parser.Peek()
+        if token.Kind != "symbol" || !SQL_COMPARISONS[token.Text] {
+          return nil, parser.Error("expected a comparison")
+        }
+        parser.Next++
+        filter = token.Text
+        value, err := parser.Literal()
+        if err != nil {
+          return nil, err
+        }
+        values = []interface{}{value}
+    }
+  }
+  node, err := ComparisonNode(parser.Schema, field, filter, values, negate)
+  if err != nil {
+    return nil, SQLError(err.Error(), parser.Statement, start.Position)
+  }
+  return node, nil
+}
+
+// field compared with literal values: = != <> < <= > >= in between like
+// null contains startswith endswith; missing values only match null
+func ComparisonNode(schema * Schema, field string, filter string, values []interface{}, negate bool) (* QueryNode, error) {
+  fieldData := schema.Properties[field]
+  getValue := GenericAccessor(fieldData)
+  node := &QueryNode{Field: field, Filter: filter, Value: ToJson(values)}
+  if negate {
+    node.Filter = "not " + filter
+  }
+
+  if filter == "null" {
+    node.Value = ""
+    if negate {
+      node.Filter = "notNull"
+    }
+    node.Predicate = func(x int) bool {
+      _, has := getValue(x)
+      return has == negate
+    }
+    return node, nil
+  }
+
+  // values must have the type of the field
+  for _, value := range values {
+    valueType := "number"
+    switch value.(type) {
+      case string:
+        valueType = "string"
+      case bool:
+        valueType = "boolean"
+    }
+    if valueType != fieldData.Type || (valueType == "boolean" && filter != "=" && filter != "!=" && filter != "<>" && filter != "in") {
+      return nil, fmt.Errorf("can not compare %s field '%s' with %s", fieldData.Type, field, ToJson(value))
+    }
+  }
+
+  var match func(interface{}) bool
+  switch filter {
+    case "=":
+      match = func(x interface{}) bool { return CompareValues(x, values[0]) == 0 }
+    case "!=", "<>":
+      match = func(x interface{}) bool { return CompareValues(x, values[0]) != 0 }
+    case "<":
+      match = func(x interface{}) bool { return CompareValues(x, values[0]) < 0 }
+    case "<=":
+      match = func(x interface{}) bool { return CompareValues(x, values[0]) <= 0 }
+    case ">":
+      match = func(x interface{}) bool { return CompareValues(x, values[0]) > 0 }
+    case ">=":
+      match = func(x interface{}) bool { return CompareValues(x, values[0]) >= 0 }
+    case "in":
+      match = func(x interface{}) bool {
+        for _, value := range values {
+          if CompareValues(x, value) == 0 {
+            return true
+          }
+        }
+        return false
+      }
+    case "between":
+      match = func(x interface{}) bool {
+        return CompareValues(x, values[0]) >= 0 && CompareValues(x, values[1]) <= 0
+      }
+    case "like", "contains", "startswith", "endswith":
+      if fieldData.Type != "string" {
+        return nil, fmt.Errorf("%s needs a string field; '%s' is %s", filter, field, fieldData.Type)
+      }
+      pattern := regexp.QuoteMeta(values[0].(string))
+      switch filter {
+        case "like":
+          pattern = "^" + strings.NewReplacer("%", ".*", "_", ".").Replace(pattern) + "$"
+        case "startswith":
+          pattern = "^" + pattern
+        case "endswith":
+          pattern = pattern + "$"
+      }
+      re := regexp.MustCompile(pattern)
+      match = func(x interface{}) bool {
+        text, isString := x.(string)
+        return isString && re.MatchString(text)
+      }
+    default:
+      return nil, fmt.Errorf("comparison '%s' is not supported", filter)
+  }
+  // missing values never match, negated or not
+  node.Predicate = func(x int) bool {
+    value, has := getValue(x)
+    return has && match(value) != negate
+  }
+  return node, nil
+}
+
+func (search * Search) SQL(statement string, schema * Schema) map[string]interface{} {
+  output, err := search.ExecuteSQL(statement, schema)
+  if err != nil {
+    return map[string]interface{}{"errors": []string{"sql: " + err.Error()}}
+  }
+  return output
+}
+
+// columns with their types and rows of values
+func (search * Search) ExecuteSQL(statement string, schema * Schema) (map[string]interface{}, error) {
+  query, err := search.ParseSQL(statement, schema)
+  if err != nil {
+    return nil, err
+  }
+  result, err := search.RunSQL(query, schema)
+  if err != nil {
+    return nil, err
+  }
+  return map[string]interface{}{"columns": result.Columns, "rows": result.Rows}, nil
+}
+
+type SQLResult struct {
+  Columns []map[string]interface{}
+  Rows [][]interface{}
+  Items []int // record of each row when not grouped
+  Total int // rows before OFFSET and LIMIT
+}
+
+func (search * Search) RunSQL(query * SQLQuery, schema * Schema) (* SQLResult, error) {
+  if len(search.TextIndexes) > 0 && schema.TotalItems > 1000000 {
+    search.Lock.Lock()
+    defer search.Lock.Unlock()
+  }
+
+  request := search.NewRequest(url.Values{})
+  results := SearchStart(schema)
+  if query.Where != nil {
+    results = search.Evaluate(request, schema, query.Where, results)
+  }
+  if len(request.Errors) > 0 {
+    return nil, fmt.Errorf("%s", strings.Join(request.Errors, "; "))
+  }
+
+  grouped := len(query.GroupBy) > 0
+  for _, column := range query.Columns {
+    grouped = grouped || column.Function != ""
+  }
+  if query.Star {
+    if grouped {
+      return nil, fmt.Errorf("SELECT * can not be grouped")
+    }
+    fields := make([]string, 0, len(schema.Properties))
+    for field, _ := range schema.Properties {
+      fields = append(fields, field)
+    }
+    sort.Strings(fields)
+    for _, field := range fields {
+      query.Columns = append(query.Columns, SQLColumn{Name: field, Field: field})
+    }
+  }
+  for _, column := range query.Columns {
+    if column.Field == "" && column.Function == "" {
+      return nil, fmt.Errorf("unknown field '%s'", column.Name)
+    }
+  }
+
+  // order keys refer to select columns by position or name; ungrouped
+  // queries may also order by any field
+  orderColumns := make([]int, len(query.OrderBy))
+  for i, order := range query.OrderBy {
+    orderColumns[i] = -1
+    if order.Position > 0 {
+      if order.Position > len(query.Columns) {
+        return nil, fmt.Errorf("ORDER BY %d is not a select column", order.Position)
+      }
+      orderColumns[i] = order.Position - 1
+      continue
+    }
+    for c, column := range query.Columns {
+      if column.Name == order.Column.Name || (order.Column.Function == "" && column.Function == "" && column.Field == order.Column.Field && column.Field != "") || (order.Column.Function != "" && column.Function == order.Column.Function && column.Field == order.Column.Field) {
+        orderColumns[i] = c
+        break
+      }
+    }
+    if orderColumns[i] == -1 && (grouped || order.Column.Field == "") {
+      return nil, fmt.Errorf("ORDER BY %s is not a select column", order.Column.Name)
+    }
+  }
+
+  columns := make([]map[string]interface{}, len(query.Columns))
+  for c, column := range query.Columns {
+    columnType := "number"
+    if column.Function == "" {
+      columnType = schema.Properties[column.Field].Type
+    }
+    columns[c] = map[string]interface{}{"name": column.Name, "type": columnType}
+  }
+
+  result := &SQLResult{Columns: columns}
+  if grouped {
+    rows, err := search.SQLGroups(query, schema, results)
+    if err != nil {
+      return nil, err
+    }
+    sort.SliceStable(rows, func(i, j int) bool {
+      for k, order := range query.OrderBy {
+        if compare := CompareValues(rows[i][orderColumns[k]], rows[j][orderColumns[k]]); compare != 0 {
+          return (compare < 0) != order.Descending
+        }
+      }
+      return false
+    })
+    result.Total = len(rows)
+    result.Rows = SQLPage(rows, query.Offset, query.Limit)
+  } else {
+    accessors := make([]func(int) (interface{}, bool), len(query.OrderBy))
+    for k, order := range query.OrderBy {
+      field := order.Column.Field
+      if orderColumns[k] != -1 {
+        field = query.Columns[orderColumns[k]].Field
+      }
+      accessors[k] = GenericAccessor(schema.Properties[field])
+    }
+    sort.SliceStable(results, func(i, j int) bool {
+      for k, order := range query.OrderBy {
+        if compare := CompareValues(SQLValue(accessors[k], results[i].Item), SQLValue(accessors[k], results[j].Item)); compare != 0 {
+          return (compare < 0) != order.Descending
+        }
+      }
+      return false
+    })
+    result.Total = len(results)
+    if query.Offset > result.Total {
+      query.Offset = result.Total
+    }
+    results = results[query.Offset:]
+    if query.Limit >= 0 && query.Limit < len(results) {
+      results = results[:query.Limit]
+    }
+    accessors = make([]func(int) (interface{}, bool), len(query.Columns))
+    for c, column := range query.Columns {
+      accessors[c] = GenericAccessor(schema.Properties[column.Field])
+    }
+    result.Rows = make([][]interface{}, len(results))
+    result.Items = make([]int, len(results))
+    for i, x := range results {
+      result.Rows[i] = make([]interface{}, len(query.Columns))
+      for c, accessor := range accessors {
+        result.Rows[i][c] = SQLValue(accessor, x.Item)
+      }
+      result.Items[i] = x.Item
+    }
+  }
+  return result, nil
+}
+
+// nil when missing
+func SQLValue(accessor func(int) (interface{}, bool), item int) interface{} {
+  if value, has := accessor(item); has {
+    return value
+  }
+  return nil
+}
+
+func (search * Search) SQLGroups(query * SQLQuery, schema * Schema, results SearchResults) ([][]interface{}, error) {
+  groupColumns := make(map[string]int)
+  for i, field := range query.GroupBy {
+    groupColumns[field] = i
+  }
+  aggregates := make([]Aggregate, 0)
+  for _, column := range query.Columns {
+    if column.Function != "" {
+      aggregates = append(aggregates, Aggregate{Function: column.Function, Field: column.Field})
+    } else if _, has := groupColumns[column.Field]; !has {
+      return nil, fmt.Errorf("%s must be in GROUP BY or an aggregate", column.Field)
+    }
+  }
+  groups, err := GroupBy(schema, query.GroupBy, aggregates, results)
+  if err != nil {
+    return nil, err
+  }
+  if len(groups) == 0 && len(query.GroupBy) == 0 {
+    // aggregates over no records still give one row
+    groups = append(groups, &GroupRow{States: make([]AggregateState, len(aggregates))})
+  }
+  rows := make([][]interface{}, len(groups))
+  for g, group := range groups {
+    values := group.Values(schema, query.GroupBy)
+    row := make([]interface{}, len(query.Columns))
+    a := 0
+    for c, column := range query.Columns {
+      if column.Function != "" {
+        row[c] = group.States[a].Value(column.Function)
+        a++
+      } else {
+        row[c] = values[groupColumns[column.Field]]
+      }
+    }
+    rows[g] = row
+  }
+  return rows, nil
+}
+
+func SQLPage(rows [][]interface{}, offset int, limit int) [][]interface{} {
+  if offset > len(rows) {
+    offset = len(rows)
+  }
+  rows = rows[offset:]
+  if limit >= 0 && limit < len(rows) {
+    rows = rows[:limit]
+  }
+  return rows
+}
@@ -0,0 +1,107 @@
+package index
+
+import (
+  "encoding/json"
+  "reflect"
+  "testing"
+)
+
+// copies the tree without predicates, which can not be compared
+func WithoutPredicates(node * QueryNode) * QueryNode {
+  if node == nil {
+    return nil
+  }
+  copied := &QueryNode{Operator: node.Operator, Field: node.Field, Filter: node.Filter, Value: node.Value}
+  for _, child := range node.Children {
+    copied.Children = append(copied.Children, WithoutPredicates(child))
+  }
+  return copied
+}
+
+func TestParseSQL(t *testing.T) {
+  collection := FixtureCollection()
+  tests := []struct {
+    statement string
+    want * SQLQuery
+    err string
+  }{
+    {"SELECT * FROM items", &SQLQuery{Star: true, From: "items", Limit: -1}, ""},
+    {"select color, count(*) AS n from ITEMS group by color order by n desc, 1 limit 2 offset 1;", &SQLQuery{
+      Columns: []SQLColumn{{Name: "color", Field: "color"}, {Name: "n", Function: "count"}},
+      From: "ITEMS",
+      GroupBy: []string{"color"},
+      OrderBy: []SQLOrder{{Column: SQLColumn{Name: "n"}, Descending: true}, {Position: 1}},
+      Limit: 2,
+      Offset: 1,
+    }, ""},
+    {"SELECT title FROM items WHERE price < 10 AND NOT brand IN ('acme', 'globex')", &SQLQuery{
+      Columns: []SQLColumn{{Name: "title", Field: "title"}},
+      From: "items",
+      Where: &QueryNode{Operator: "and", Children: []*QueryNode{
+        {Field: "price", Filter: "<", Value: "[10]"},
+        {Operator: "not", Children: []*QueryNode{{Field: "brand", Filter: "in", Value: `["acme","globex"]`}}},
+      }},
+      Limit: -1,
+    }, ""},
+    {"SELECT title FROM x", nil, "unknown table 'x', expected items at character 19"},
+    {"SELECT title FROM", nil, "expected a name at character 18"},
+    {"DELETE FROM items", nil, "expected SELECT instead of 'DELETE' at character 1"},
+    {"SELECT sum(color) FROM items", nil, "sum needs a number field; 'color' is string at character 8"},
+    {"SELECT title FROM items WHERE", nil, "expected a name at character 30"},
+    {"SELECT title FROM items WHERE price = 'a'", nil, "can not compare number field 'price' with \"a\" at character 31"},
+    {"SELECT title FROM items WHERE title = 'it''s", nil, "unterminated string at character 39"},
+    {"SELECT title FROM items LIMIT -1", nil, "expected a whole number at character 31"},
+    {"SELECT title FROM items extra", nil, "unexpected 'extra' at character 25"},
+  }
+  for _, test := range tests {
+    query, err := collection.search.ParseSQL(test.statement, collection.schema)
+    if test.err != "" {
+      if err == nil || err.Error() != test.err {
+        t.Errorf("ParseSQL(%q) error = %v, want %q", test.statement, err, test.err)
+      }
+      continue
+    }
+    if err != nil {
+      t.Errorf("ParseSQL(%q) error = %v", test.statement, err)
+      continue
+    }
+    got := *query
+    got.Where = WithoutPredicates(query.Where)
+    if !reflect.DeepEqual(&got, test.want) {
+      where, _ := json.Marshal(got.Where)
+      t.Errorf("ParseSQL(%q) = %+v where %s, want %+v", test.statement, got, where, *test.want)
+    }
+  }
+}
+
+func TestExecuteSQL(t *testing.T) {
+  collection := FixtureCollection()
+  tests := []struct {
+    statement string
+    rows [][]interface{}
+    err string
+  }{
+    {"SELECT color, count(*) FROM items GROUP BY color ORDER BY color", [][]interface{}{{"blue", 133}, {"green", 133}, {"red", 134}}, ""},
+    {"SELECT count(*), min(price), max(price) FROM items WHERE price IS NULL", [][]interface{}{{58, nil, nil}}, ""},
+    {"SELECT count(*) FROM items WHERE price > 1000", [][]interface{}{{0}}, ""},
+    {"SELECT popularity FROM items WHERE popularity BETWEEN 10 AND 12 ORDER BY popularity DESC", [][]interface{}{{12.0}, {11.0}, {10.0}}, ""},
+    {"SELECT popularity FROM items WHERE popularity < 20 AND brand = 'acme' AND NOT color = 'red' ORDER BY 1", [][]interface{}{{4.0}, {8.0}, {16.0}}, ""},
+    {"SELECT popularity FROM items ORDER BY popularity LIMIT 2 OFFSET 398", [][]interface{}{{398.0}, {399.0}}, ""},
+    {"SELECT popularity FROM items ORDER BY popularity LIMIT 2 OFFSET 400", [][]interface{}{}, ""},
+    {"SELECT count(*) FROM items WHERE title LIKE 'Tele%'", [][]interface{}{{134}}, ""},
+    {"SELECT count(*) AS n, flag FROM items GROUP BY flag ORDER BY n DESC, flag", [][]interface{}{{200, false}, {200, true}}, ""},
+    {"SELECT nope FROM items", nil, "unknown field 'nope'"},
+    {"SELECT color, price FROM items GROUP BY color", nil, "price must be in GROUP BY or an aggregate"},
+    {"SELECT title FROM items ORDER BY zz", nil, "ORDER BY zz is not a select column"},
+  }
+  for _, test := range tests {
+    output, err := collection.search.ExecuteSQL(test.statement, collection.schema)
+    if test.err != "" {
+      if err == nil || err.Error() != test.err {
+        t.Errorf("ExecuteSQL(%q) error = %v, want %q", test.statement, err, test.err)
+      }
+    } else if err != nil || !reflect.DeepEqual(output["rows"], test.rows) {
+      t.Errorf("ExecuteSQL(%q) rows = %v, %v, want %v", test.statement, output["rows"], err, test.rows)
+    }
+  }
+}
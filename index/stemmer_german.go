@@ -0,0 +1,121 @@
+package index
+
+import (
+  "strings"
+)
+
+// snowball german stemmer, which kljensen/snowball does not have; see
+// snowballstem.org/algorithms/german/stemmer.html
+
+func GermanIsVowel(item rune) bool {
+  return strings.ContainsRune("aeiouyäöü", item)
+}
+
+func GermanStem(word string) string {
+  w := []rune(strings.ReplaceAll(word, "ß", "ss"))
+
+  // u and y between vowels are treated as consonants
+  for i := 1; i < len(w) - 1; i++ {
+    if (w[i] == 'u' || w[i] == 'y') && GermanIsVowel(w[i - 1]) && GermanIsVowel(w[i + 1]) {
+      w[i] = w[i] - 'a' + 'A'
+    }
+  }
+
+  region := func(start int) int {
+    for i := start + 1; i < len(w); i++ {
+      if !GermanIsVowel(w[i]) && GermanIsVowel(w[i - 1]) {
+        return i + 1
+      }
+    }
+    return len(w)
+  }
+  r1 := region(0)
+  r2 := region(r1)
+  if r1 < 3 {
+    r1 = 3
+  }
+  if r1 > len(w) {
+    r1 = len(w)
+  }
+
+  // longest matching suffix
+  suffix := func(suffixes ...string) string {
+    for _, s := range suffixes {
+      if strings.HasSuffix(string(w), s) {
+        return s
+      }
+    }
+    return ""
+  }
+  start := func(s string) int {
+    return len(w) - len([]rune(s))
+  }
+  has := func(s string) bool {
+    return strings.HasSuffix(string(w), s)
+  }
+  trim := func(s string) {
+    w = w[:start(s)]
+  }
+
+  // step 1
+  switch s := suffix("ern", "em", "er", "en", "es", "e", "s"); s {
+    case "em", "ern", "er":
+      if start(s) >= r1 {
+        trim(s)
+      }
+    case "e", "en", "es":
+      if start(s) >= r1 {
+        trim(s)
+        if has("niss") {
+          trim("s")
+        }
+      }
+    case "s":
+      if start(s) >= r1 && start(s) > 0 && strings.ContainsRune("bdfghklmnrt", w[start(s) - 1]) {
+        trim(s)
+      }
+  }
+
+  // step 2
+  switch s := suffix("est", "en", "er", "st"); s {
+    case "en", "er", "est":
+      if start(s) >= r1 {
+        trim(s)
+      }
+    case "st":
+      if start(s) >= r1 && start(s) > 3 && strings.ContainsRune("bdfghklmnt", w[start(s) - 1]) {
+        trim(s)
+      }
+  }
+
+  // step 3
+  switch s := suffix("isch", "lich", "heit", "keit", "end", "ung", "ig", "ik"); s {
+    case "end", "ung":
+      if start(s) >= r2 {
+        trim(s)
+        if has("ig") && start("ig") >= r2 && !has("eig") {
+          trim("ig")
+        }
+      }
+    case "ig", "ik", "isch":
+      if start(s) >= r2 && !has("e" + s) {
+        trim(s)
+      }
+    case "lich", "heit":
+      if start(s) >= r2 {
+        trim(s)
+        if e := suffix("er", "en"); e != "" && start(e) >= r1 {
+          trim(e)
+        }
+      }
+    case "keit":
+      if start(s) >= r2 {
+        trim(s)
+        if e := suffix("lich", "ig"); e != "" && start(e) >= r2 {
+          trim(e)
+        }
+      }
+  }
+
+  return strings.NewReplacer("U", "u", "Y", "y", "ä", "a", "ö", "o", "ü", "u").Replace(string(w))
+}
@@ -0,0 +1,66 @@
+package index
+
+import (
+  "testing"
+)
+
+// expected stems are the output of the reference snowball german stemmer
+func TestGermanStem(t *testing.T) {
+  tests := []struct {
+    word string
+    want string
+  }{
+    // umlauts are removed after stemming, ß becomes ss
+    {"häuser", "haus"},
+    {"häusern", "haus"},
+    {"mädchen", "madch"},
+    {"prüfungen", "prufung"},
+    {"straße", "strass"},
+    {"weißen", "weiss"},
+    {"größte", "grosst"},
+    {"größer", "gross"},
+    {"flüsse", "fluss"},
+    // u and y between vowels are consonants
+    {"bauen", "bau"},
+    {"treue", "treu"},
+    {"feuer", "feu"},
+    {"bayern", "bay"},
+    // r1 starts at the third letter at the earliest
+    {"ende", "end"},
+    {"erden", "erd"},
+    {"ei", "ei"},
+    {"aus", "aus"},
+    // s only after b, d, f, g, h, k, l, m, n, r or t
+    {"kurs", "kur"},
+    {"buchs", "buch"},
+    {"kenntnis", "kenntnis"},
+    {"abends", "abend"},
+    // step 1 and 2 endings
+    {"kindes", "kind"},
+    {"bettes", "bett"},
+    {"erste", "erst"},
+    {"kleinst", "klein"},
+    {"aufeinanderfolgenden", "aufeinanderfolg"},
+    // step 3 derivational endings in r2
+    {"arbeitend", "arbeit"},
+    {"lebendig", "lebend"},
+    {"heiligkeit", "heilig"},
+    {"fähigkeit", "fahig"},
+    {"einigkeit", "einig"},
+    {"ähnlichkeit", "ahnlich"},
+    {"eigentümlichkeit", "eigentum"},
+    {"schönheit", "schonheit"},
+    {"gesetzlich", "gesetz"},
+    {"enderlich", "end"},
+    {"endlich", "endlich"},
+    {"kategorischen", "kategor"},
+    {"heidnisch", "heidnisch"},
+    {"bildung", "bildung"},
+    {"ratlos", "ratlos"},
+  }
+  for _, test := range tests {
+    if got := GermanStem(test.word); got != test.want {
+      t.Errorf("GermanStem(%q) = %q, want %q", test.word, got, test.want)
+    }
+  }
+}
@@ -0,0 +1,77 @@
+package index
+
+// based on the snowball stop word lists
+
+var stopWords = map[string]string{
+  "english": `
+    i me my myself we our ours ourselves you your yours yourself yourselves he him his himself she her hers
+    herself it its itself they them their theirs themselves what which who whom this that these those am is
+    are was were be been being have has had having do does did doing would should could ought a an the and
+    but if or because as until while of at by for with about against between into through during before after
+    above below to from up down in out on off over under again further then once here there when where why
+    how all any both each few more most other some such no nor not only own same so than too very`,
+  "german": `
+    aber alle allem allen aller alles als also am an ander andere anderem anderen anderer anderes anderm
+    andern anders auch auf aus bei bin bis bist da damit dann der den des dem die das dass daß derselbe
+    derselben denselben desselben demselben dieselbe dieselben dasselbe dazu dein deine deinem deinen deiner
+    deines denn derer dessen dich dir du dies diese diesem diesen dieser dieses doch dort durch ein eine einem
+    einen einer eines einig einige einigem einigen einiger einiges einmal er ihn ihm es etwas euer eure eurem
+    euren eurer eures für gegen gewesen hab habe haben hat hatte hatten hier hin hinter ich mich mir ihr ihre
+    ihrem ihren ihrer ihres euch im in indem ins ist jede jedem jeden jeder jedes jene jenem jenen jener jenes
+    jetzt kann kein keine keinem keinen keiner keines können könnte machen man manche manchem manchen mancher
+    manches mein meine meinem meinen meiner meines mit muss musste nach nicht nichts noch nun nur ob oder ohne
+    sehr sein seine seinem seinen seiner seines selbst sich sie ihnen sind so solche solchem solchen solcher
+    solches soll sollte sondern sonst über um und uns unser unsere unserem unseren unserer unseres unter viel
+    vom von vor während war waren warst was weg weil weiter welche welchem welchen welcher welches wenn werde
+    werden wie wieder will wir wird wirst wo wollen wollte würde würden zu zum zur zwar zwischen`,
+  "french": `
+    au aux avec ce ces dans de des du elle en et eux il ils je la le les leur leurs lui ma mais me même mes
+    moi mon ne nos notre nous on ou par pas pour qu que qui sa se ses son sur ta te tes toi ton tu un une vos
+    votre vous c d j l à m n s t y été étée étées étés étant suis es est sommes êtes sont serai seras sera
+    serons serez seront serais serait serions seriez seraient étais était étions étiez étaient fus fut fûmes
+    fûtes furent sois soit soyons soyez soient fusse fusses fût fussions fussiez fussent ayant eu eue eues eus
+    ai as avons avez ont aurai auras aura aurons aurez auront aurais aurait aurions auriez auraient avais
+    avait avions aviez avaient eut eûmes eûtes eurent aie aies ait ayons ayez aient eusse eusses eût eussions
+    eussiez eussent ceci cela cet cette ici quel quels quelle quelles sans soi`,
+  "spanish": `
+    de la que el en y a los del se las por un para con no una su al lo como más pero sus le ya o este sí
+    porque esta entre cuando muy sin sobre también me hasta hay donde quien desde todo nos durante todos uno
+    les ni contra otros ese eso ante ellos e esto mí antes algunos qué unos yo otro otras otra él tanto esa
+    estos mucho quienes nada muchos cual poco ella estar estas algunas algo nosotros mi mis tú te ti tu tus
+    ellas nosotras vosotros vosotras os mío mía míos mías tuyo tuya tuyos tuyas suyo suya suyos suyas nuestro
+    nuestra nuestros nuestras vuestro vuestra vuestros vuestras esos esas estoy estás está estamos estáis
+    están es son fue era ser he ha han había`,
+  "russian": `
+    и в во не что он на я с со как а то все она так его но да ты к у же вы за бы по только ее мне было вот
+    от меня еще нет о из ему теперь когда даже ну вдруг ли если уже или ни быть был него до вас нибудь опять
+    уж вам ведь там потом себя ничего ей может они тут где есть надо ней для мы тебя их чем была сам чтоб без
+    будто чего раз тоже себе под будет ж тогда кто этот того потому этого какой совсем ним здесь этом один
+    почти мой тем чтобы нее сейчас были куда зачем всех никогда можно при наконец два об другой хоть после над
+    больше тот через эти нас про всего них какая много разве три эту моя впрочем хорошо свою этой перед иногда
+    лучше чуть том нельзя такой им более всегда конечно всю между`,
+  "swedish": `
+    och det att i en jag hon som han på den med var sig för så till är men ett om hade de av icke mig du
+    henne då sin nu har inte hans honom skulle hennes där min man ej vid kunde något från ut när efter upp vi
+    dem vara vad över än dig kan sina här ha mot alla under någon eller allt mycket sedan ju denna själv detta
+    åt utan varit hur ingen mitt ni bli blev oss din dessa några deras blir mina samma vilken er sådan vår
+    blivit dess inom mellan sådant varför varje vilka ditt vem vilket sitta sådana vart dina vars vårt våra
+    ert era vilkas`,
+  "norwegian": `
+    og i jeg det at en et den til er som på de med han av ikke ikkje der så var meg seg men ett har om vi
+    min mitt ha hadde hun nå over da ved fra du ut sin dem oss opp man kan hans hvor eller hva skal selv sjøl
+    her alle vil bli ble blei blitt kunne inn når være kom noen noe ville dere deres kun ja etter ned skulle
+    denne for deg si sine sitt mot å meget hvorfor dette disse uten hvordan ingen din ditt blir samme hvilken
+    hvilke sånn inni mellom vår hver hvem vors hvis både bare enn fordi før mange også slik vært begge siden`,
+  "hungarian": `
+    a ahogy ahol aki akik akkor alatt által általában amely amelyek amelyekben amelyeket amelyet amelynek ami
+    amit amolyan amíg amikor át abban ahhoz annak arra arról az azok azon azt azzal azért aztán azután azonban
+    bár be belül benne csak de e eddig egész egy egyes egyetlen egyéb egyik egyre ekkor el elég ellen elő
+    először előtt első én éppen ebben ehhez emilyen ennek erre ez ezt ezek ezen ezzel ezért és fel felé hanem
+    hiszen hogy hogyan igen így illetve ilyen ilyenkor ismét itt jó jól jobban kell kellett keresztül ki
+    kívül között közül legalább lehet lehetett legyen lenne lenni lesz lett maga magát majd már más másik meg
+    még mellett mert mely melyek mi mit míg miért milyen mikor minden mindent mindenki mindig mint mintha mivel
+    most nagy nagyobb nagyon ne néha nekem neki nem néhány nélkül nincs olyan ott össze ő ők őket pedig persze
+    rá s saját sem semmi sok sokat sokkal számára szemben szerint szinte talán tehát teljes tovább továbbá több
+    úgy ugyanis új újabb újra után utána utolsó vagy vagyis valaki valami valamint való vagyok van vannak volt
+    voltam voltak voltunk vissza vele viszont volna`,
+}
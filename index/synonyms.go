@@ -0,0 +1,101 @@
+package index
+
+import (
+  "io/ioutil"
+  "strings"
+)
+
+// solr style synonyms, expanded at query time:
+//   tv, television, telly    each matches all
+//   tv => television         tv is replaced by television
+
+type Synonyms struct {
+  Rules map[string][][]string
+  MaxLength int
+}
+
+func SynonymWords(entry string) []string {
+  words := make([]string, 0, 2)
+  for _, token := range Tokenize(entry) {
+    if IsWord(token.Text) {
+      words = append(words, token.Text)
+    }
+  }
+  return words
+}
+
+func SynonymKey(words []string) string {
+  return strings.ToLower(FoldAccents(strings.Join(words, " ")))
+}
+
+func (synonyms * Synonyms) Add(from []string, to [][]string) {
+  if len(from) == 0 {
+    return
+  }
+  key := SynonymKey(from)
+  for _, words := range to {
+    duplicate := false
+    for _, existing := range synonyms.Rules[key] {
+      duplicate = duplicate || SynonymKey(existing) == SynonymKey(words)
+    }
+    if !duplicate && len(words) > 0 {
+      synonyms.Rules[key] = append(synonyms.Rules[key], words)
+    }
+  }
+  if len(from) > synonyms.MaxLength {
+    synonyms.MaxLength = len(from)
+  }
+}
+
+func ParseSynonyms(text string) * Synonyms {
+  synonyms := &Synonyms{Rules: make(map[string][][]string)}
+  entries := func(list string) [][]string {
+    output := make([][]string, 0, 4)
+    for _, entry := range strings.Split(list, ",") {
+      if words := SynonymWords(entry); len(words) > 0 {
+        output = append(output, words)
+      }
+    }
+    return output
+  }
+  for _, line := range strings.Split(text, "\n") {
+    line = strings.TrimSpace(line)
+    if len(line) == 0 || strings.HasPrefix(line, "#") {
+      continue
+    }
+    if i := strings.Index(line, "=>"); i != -1 {
+      to := entries(line[i+2:])
+      for _, from := range entries(line[:i]) {
+        synonyms.Add(from, to)
+      }
+    } else {
+      all := entries(line)
+      for _, from := range all {
+        synonyms.Add(from, all)
+      }
+    }
+  }
+  return synonyms
+}
+
+func LoadSynonyms(localfile string) (* Synonyms, error) {
+  file, err := ioutil.ReadFile(localfile); if err != nil { return nil, err }
+  return ParseSynonyms(string(file)), nil
+}
+
+// longest rule matching the words starting at token i; returns words consumed
+func (synonyms * Synonyms) Match(tokens []Token, i int) (int, [][]string) {
+  if synonyms == nil {
+    return 0, nil
+  }
+  words := make([]string, 0, synonyms.MaxLength)
+  for j := i; j < len(tokens) && len(words) < synonyms.MaxLength && IsWord(tokens[j].Text); j++ {
+    words = append(words, tokens[j].Text)
+  }
+  for n := len(words); n > 0; n-- {
+    if expansions, has := synonyms.Rules[SynonymKey(words[:n])]; has {
+      return n, expansions
+    }
+  }
+  return 0, nil
+}
@@ -0,0 +1,110 @@
+package index
+
+import (
+  "net/url"
+  "reflect"
+  "testing"
+)
+
+const SYNONYMS_FIXTURE = `# comment
+tv, television, telly
+
+TV => tv set
+New York, NYC
+  # indented comment
+colour => color, hue
+Café, cafe
+`
+
+func TestParseSynonyms(t *testing.T) {
+  synonyms := ParseSynonyms(SYNONYMS_FIXTURE)
+  equivalent := [][]string{{"tv"}, {"television"}, {"telly"}}
+  want := map[string][][]string{
+    // one way rules add to equivalences
+    "tv": {{"tv"}, {"television"}, {"telly"}, {"tv", "set"}},
+    "television": equivalent,
+    "telly": equivalent,
+    "new york": {{"New", "York"}, {"NYC"}},
+    "nyc": {{"New", "York"}, {"NYC"}},
+    "colour": {{"color"}, {"hue"}},
+    // keys fold case and accents; duplicates are left out
+    "cafe": {{"Café"}},
+  }
+  if !reflect.DeepEqual(synonyms.Rules, want) {
+    t.Errorf("rules = %q, want %q", synonyms.Rules, want)
+  }
+  if synonyms.MaxLength != 2 {
+    t.Errorf("MaxLength = %d, want 2", synonyms.MaxLength)
+  }
+  if empty := ParseSynonyms("\n# only comments\n => x\n"); len(empty.Rules) != 0 {
+    t.Errorf("rules = %q, want none", empty.Rules)
+  }
+}
+
+func TestSynonymMatch(t *testing.T) {
+  synonyms := ParseSynonyms(SYNONYMS_FIXTURE)
+  tests := []struct {
+    text string
+    i int
+    length int
+    want [][]string
+  }{
+    {"New York pizza", 0, 2, [][]string{{"New", "York"}, {"NYC"}}},
+    {"new york", 1, 0, nil},
+    {"new", 0, 0, nil},
+    {"cheap TV", 1, 1, [][]string{{"tv"}, {"television"}, {"telly"}, {"tv", "set"}}},
+    {"CAFÉ", 0, 1, [][]string{{"Café"}}},
+    {"colour", 0, 1, [][]string{{"color"}, {"hue"}}},
+    // one way: color is not replaced by colour
+    {"color", 0, 0, nil},
+  }
+  for _, test := range tests {
+    length, expansions := synonyms.Match(Tokenize(test.text), test.i)
+    if length != test.length || !reflect.DeepEqual(expansions, test.want) {
+      t.Errorf("Match(%q, %d) = %d, %q, want %d, %q", test.text, test.i, length, expansions, test.length, test.want)
+    }
+  }
+  var none * Synonyms
+  if length, _ := none.Match(Tokenize("tv"), 0); length != 0 {
+    t.Errorf("nil synonyms matched %d words", length)
+  }
+}
+
+// multi word expansions match as phrases, so record 2 does not match; explain
+// shows the expansion
+func TestSynonymSearch(t *testing.T) {
+  collection := Index(map[string][]interface{}{"body": {
+    []interface{}{"flights to new york"},
+    []interface{}{"nyc hotels"},
+    []interface{}{"york and new"},
+    []interface{}{"hue of the sky"},
+  }}, Options{Language: "none", FoldCase: true, Synonyms: ParseSynonyms(SYNONYMS_FIXTURE)})
+  collection.Wait()
+  tests := []struct {
+    search string
+    ids []int
+  }{
+    {"nyc", []int{1, 0}},
+    {"New York", []int{1, 0}},
+    {"colour", []int{3}},
+    {"color", []int{}},
+  }
+  for _, test := range tests {
+    output := collection.search.Search(url.Values{"search": {test.search}}, collection.schema)
+    ids := make([]int, 0)
+    for _, result := range output["results"].([]map[string]interface{}) {
+      ids = append(ids, result["id"].(int))
+    }
+    if !reflect.DeepEqual(ids, test.ids) {
+      t.Errorf("search=%s: %v, want %v", test.search, ids, test.ids)
+    }
+  }
+
+  output := collection.search.Search(url.Values{"search": {"NYC"}, "explain": {"true"}}, collection.schema)
+  want := []map[string]interface{}{{"field": "search", "query": "NYC", "clauses": []TextClause{
+    {Alternatives: [][]string{{"new", "york"}, {"nyc"}}, Synonym: "NYC"},
+  }}}
+  if !reflect.DeepEqual(output["explain"], want) {
+    t.Errorf("explain = %v, want %v", output["explain"], want)
+  }
+}
@@ -0,0 +1,137 @@
+package index
+
+// positional full text index of one field; records hold term codes in word
+// order so phrases never cross fields
+
+const TEXT_GAP int32 = -1 // between array / object values so phrases do not span them
+
+type TextIndex struct {
+  Terms StringSet
+  Records [][]int32
+  WordCounts []int
+  // term by code, records containing each term and total record length
+  TermList []string
+  Documents []int
+  RecordCount int
+  TotalLength int
+  Weight float64
+}
+
+func NewTextIndex(totalItems int, weight float64) * TextIndex {
+  return &TextIndex{Terms: make(StringSet), Records: make([][]int32, totalItems), WordCounts: make([]int, 0), TermList: make([]string, 0), Documents: make([]int, 0), Weight: weight}
+}
+
+// terms of each value; values are separated by a gap
+func (textIndex * TextIndex) AddRecord(item int, values [][]string) {
+  record := make([]int32, 0, 16)
+  seen := make(map[int]bool)
+  for _, terms := range values {
+    if len(record) > 0 {
+      record = append(record, TEXT_GAP)
+    }
+    for _, term := range terms {
+      if IsWord(term) {
+        code := textIndex.Terms.AddToSet(term)
+        if code == len(textIndex.WordCounts) {
+          textIndex.WordCounts = append(textIndex.WordCounts, 0)
+          textIndex.TermList = append(textIndex.TermList, term)
+          textIndex.Documents = append(textIndex.Documents, 0)
+        }
+        textIndex.WordCounts[code]++
+        if !seen[code] {
+          seen[code] = true
+          textIndex.Documents[code]++
+        }
+        record = append(record, int32(code))
+      }
+    }
+  }
+  textIndex.Records[item] = record
+  if len(seen) > 0 {
+    textIndex.RecordCount++
+    textIndex.TotalLength += textIndex.Length(record)
+  }
+}
+
+// nil when any term does not occur in this field
+func (textIndex * TextIndex) Codes(terms []string) []int32 {
+  codes := make([]int32, len(terms))
+  for i, term := range terms {
+    code := textIndex.Terms.IndexOf(term)
+    if code == -1 {
+      return nil
+    }
+    codes[i] = int32(code)
+  }
+  return codes
+}
+
+func (textIndex * TextIndex) Length(record []int32) int {
+  length := 0
+  for _, code := range record {
+    if code != TEXT_GAP {
+      length++
+    }
+  }
+  return length
+}
+
+// best alternative; returns its weighted match count and first term code
+func (textIndex * TextIndex) Match(record []int32, alternatives [][]int32, slop int) (float64, int32) {
+  best := 0.0
+  bestCode := TEXT_GAP
+  for _, codes := range alternatives {
+    if count := MatchPhrase(record, codes, slop); count > best {
+      best = count
+      bestCode = codes[0]
+    }
+  }
+  return best, bestCode
+}
+
+// exact phrase when slop is 0, otherwise all words within slop extra words
+// in any order; closer matches count more
+func MatchPhrase(record []int32, codes []int32, slop int) float64 {
+  count := 0.0
+  if slop == 0 {
+    for p := 0; p + len(codes) <= len(record); p++ {
+      match := true
+      for k, code := range codes {
+        if record[p + k] != code {
+          match = false
+          break
+        }
+      }
+      if match {
+        count++
+      }
+    }
+    return count
+  }
+  used := make([]bool, len(codes))
+  for p := 0; p < len(record); p++ {
+    for k := range used {
+      used[k] = false
+    }
+    left := len(codes)
+    for q := p; q < len(record) && q < p + len(codes) + slop && record[q] != TEXT_GAP; q++ {
+      found := false
+      for k, code := range codes {
+        if !used[k] && code == record[q] {
+          used[k] = true
+          found = true
+          left--
+          break
+        }
+      }
+      if q == p && !found {
+        break
+      }
+      if left == 0 {
+        count += 1.0 / float64(1 + q - p + 1 - len(codes))
+        break
+      }
+    }
+  }
+  return count
+}
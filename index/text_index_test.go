@@ -0,0 +1,71 @@
+package index
+
+import (
+  "net/url"
+  "reflect"
+  "testing"
+)
+
+func TestMatchPhrase(t *testing.T) {
+  record := []int32{1, 2, 3, 4, 5, TEXT_GAP, 6, 7, 1, 2}
+  tests := []struct {
+    codes []int32
+    slop int
+    want float64
+  }{
+    {[]int32{2, 3}, 0, 1},
+    {[]int32{1, 2}, 0, 2},
+    {[]int32{1, 2, 3}, 0, 1},
+    {[]int32{3, 2}, 0, 0},
+    {[]int32{1, 3}, 0, 0},
+    // phrases do not span values
+    {[]int32{5, 6}, 0, 0},
+    {[]int32{5, 6}, 5, 0},
+    // in any order, counting less the more words are between
+    {[]int32{1, 3}, 1, 0.5},
+    {[]int32{3, 1}, 1, 0.5},
+    {[]int32{1, 4}, 1, 0},
+    {[]int32{1, 4}, 2, 1.0 / 3},
+    {[]int32{2, 3}, 2, 1},
+    {[]int32{7, 2}, 1, 0.5},
+  }
+  for _, test := range tests {
+    if got := MatchPhrase(record, test.codes, test.slop); got != test.want {
+      t.Errorf("MatchPhrase(%v, %d) = %v, want %v", test.codes, test.slop, got, test.want)
+    }
+  }
+}
+
+func TestPhraseSearch(t *testing.T) {
+  collection := Index(map[string][]interface{}{"body": {
+    []interface{}{"apple pie"},
+    []interface{}{"red apple", "pie"},
+    []interface{}{"pie made of apple"},
+    []interface{}{"apple and pie"},
+    []interface{}{"apple pie apple pie"},
+  }}, Options{Language: "none"})
+  collection.Wait()
+  tests := []struct {
+    search string
+    ids []int
+  }{
+    {`"apple pie"`, []int{4, 0}},
+    {`"pie apple"`, []int{4}},
+    // closer matches rank higher
+    {`"apple pie"~1`, []int{4, 0, 3}},
+    {`"apple pie"~2`, []int{4, 0, 3, 2}},
+    // the words of record 1 are in different array elements
+    {`"apple pie"~9`, []int{4, 0, 3, 2}},
+    {`"red apple"`, []int{1}},
+  }
+  for _, test := range tests {
+    output := collection.search.Search(url.Values{"search": {test.search}}, collection.schema)
+    ids := make([]int, 0)
+    for _, result := range output["results"].([]map[string]interface{}) {
+      ids = append(ids, result["id"].(int))
+    }
+    if !reflect.DeepEqual(ids, test.ids) || output["errors"] != nil {
+      t.Errorf("search=%s: %v, errors %v, want %v", test.search, ids, output["errors"], test.ids)
+    }
+  }
+}
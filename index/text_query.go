@@ -0,0 +1,103 @@
+package index
+
+import (
+  "strconv"
+  "strings"
+)
+
+// parsed full text query; each clause lists stemmed alternatives, one per
+// language present in the index. "a b"~3 matches a and b within 3 words.
+
+type TextClause struct {
+  Alternatives [][]string `json:"alternatives"`
+  Phrase bool `json:"phrase,omitempty"`
+  Slop int `json:"slop,omitempty"`
+  Exclude bool `json:"exclude,omitempty"`
+  Synonym string `json:"synonym,omitempty"`
+}
+
+func IsQuote(str string) bool {
+  return str == "\"" || str == "“" || str == "”"
+}
+
+func (search * Search) TextAlternatives(words []string) [][]string {
+  languages := search.TextLanguages
+  if len(languages) == 0 {
+    languages = []*Language{search.Analyzer.Language}
+  }
+  alternatives := make([][]string, 0, len(languages))
+  for _, language := range languages {
+    analyzer := search.Analyzer
+    analyzer.Language = language
+    terms := make([]string, 0, len(words))
+    for _, word := range words {
+      if term, keep := analyzer.Term(word); keep {
+        terms = append(terms, term)
+      }
+    }
+    if len(terms) > 0 {
+      duplicate := false
+      for _, x := range alternatives {
+        duplicate = duplicate || strings.Join(x, " ") == strings.Join(terms, " ")
+      }
+      if !duplicate {
+        alternatives = append(alternatives, terms)
+      }
+    }
+  }
+  return alternatives
+}
+
+func (search * Search) ParseTextQuery(value string) []TextClause {
+  tokens := Tokenize(value)
+  clauses := make([]TextClause, 0, len(tokens))
+  add := func(clause TextClause) {
+    if len(clause.Alternatives) > 0 {
+      clauses = append(clauses, clause)
+    }
+  }
+  for i := 0; i < len(tokens); i++ {
+    token := tokens[i]
+    if IsQuote(token.Text) {
+      words := make([]string, 0, 4)
+      for i++; i < len(tokens) && !IsQuote(tokens[i].Text); i++ {
+        if IsWord(tokens[i].Text) {
+          words = append(words, tokens[i].Text)
+          add(TextClause{Alternatives: search.TextAlternatives([]string{tokens[i].Text})})
+        }
+      }
+      slop := 0
+      if i + 2 < len(tokens) && tokens[i + 1].Text == "~" {
+        if distance, err := strconv.Atoi(tokens[i + 2].Text); err == nil && distance >= 0 {
+          slop = distance
+          i += 2
+        }
+      }
+      if len(words) > 1 {
+        add(TextClause{Alternatives: search.TextAlternatives(words), Phrase: true, Slop: slop})
+      }
+    } else if token.Text == "-" && (token.Start == 0 || value[token.Start - 1] == ' ') && i + 1 < len(tokens) && tokens[i + 1].Start == token.End && IsWord(tokens[i + 1].Text) {
+      i++
+      add(TextClause{Alternatives: search.TextAlternatives([]string{tokens[i].Text}), Exclude: true})
+    } else if length, expansions := search.Synonyms.Match(tokens, i); length > 0 {
+      // multi word alternatives must match as phrases
+      clause := TextClause{Synonym: value[token.Start:tokens[i + length - 1].End]}
+      for _, expansion := range expansions {
+        for _, alternative := range search.TextAlternatives(expansion) {
+          duplicate := false
+          for _, x := range clause.Alternatives {
+            duplicate = duplicate || strings.Join(x, " ") == strings.Join(alternative, " ")
+          }
+          if !duplicate {
+            clause.Alternatives = append(clause.Alternatives, alternative)
+          }
+        }
+      }
+      add(clause)
+      i += length - 1
+    } else if IsWord(token.Text) {
+      add(TextClause{Alternatives: search.TextAlternatives([]string{token.Text})})
+    }
+  }
+  return clauses
+}
@@ -0,0 +1,226 @@
+package index
+
+import (
+  "unicode"
+  "unicode/utf8"
+)
+
+// word break properties from unicode standard annex #29
+const (
+  WB_OTHER = iota
+  WB_CR
+  WB_LF
+  WB_NEWLINE
+  WB_EXTEND
+  WB_ZWJ
+  WB_FORMAT
+  WB_KATAKANA
+  WB_HEBREW_LETTER
+  WB_ALETTER
+  WB_SINGLE_QUOTE
+  WB_DOUBLE_QUOTE
+  WB_MID_NUM_LET
+  WB_MID_LETTER
+  WB_MID_NUM
+  WB_NUMERIC
+  WB_EXTEND_NUM_LET
+  WB_WSEG_SPACE
+)
+
+type Token struct {
+  Text string
+  Start int
+  End int
+}
+
+func WordBreakProperty(item rune) int {
+  switch item {
+    case '\r':
+      return WB_CR
+    case '\n':
+      return WB_LF
+    case '\v', '\f', '\u0085', '\u2028', '\u2029':
+      return WB_NEWLINE
+    case '\u200D':
+      return WB_ZWJ
+    case '\u200C':
+      return WB_EXTEND
+    case '\'':
+      return WB_SINGLE_QUOTE
+    case '"':
+      return WB_DOUBLE_QUOTE
+    case '.', '\u2018', '\u2019', '\u2024', '\uFE52', '\uFF07', '\uFF0E':
+      return WB_MID_NUM_LET
+    case ':', '\u00B7', '\u0387', '\u055F', '\u05F4', '\u2027', '\uFE13', '\uFE55', '\uFF1A':
+      return WB_MID_LETTER
+    case ',', ';', '\u037E', '\u0589', '\u060C', '\u060D', '\u066C', '\u07F8', '\u2044', '\uFE10', '\uFE14', '\uFE50', '\uFE54', '\uFF0C', '\uFF1B':
+      return WB_MID_NUM
+    case '\u066B':
+      return WB_NUMERIC
+    case '\u202F':
+      return WB_EXTEND_NUM_LET
+    case '\u3031', '\u3032', '\u3033', '\u3034', '\u3035', '\u309B', '\u309C', '\u30A0', '\u30FC', '\uFF70':
+      return WB_KATAKANA
+  }
+  switch {
+    case unicode.In(item, unicode.Mn, unicode.Me, unicode.Mc):
+      return WB_EXTEND
+    case unicode.Is(unicode.Cf, item):
+      return WB_FORMAT
+    case unicode.Is(unicode.Nd, item):
+      return WB_NUMERIC
+    case unicode.Is(unicode.Pc, item):
+      return WB_EXTEND_NUM_LET
+    case unicode.Is(unicode.Zs, item):
+      return WB_WSEG_SPACE
+    case unicode.Is(unicode.Katakana, item):
+      return WB_KATAKANA
+    case unicode.In(item, unicode.Han, unicode.Hiragana):
+      // ideographs break on every character
+      return WB_OTHER
+    case unicode.IsLetter(item):
+      if unicode.Is(unicode.Hebrew, item) {
+        return WB_HEBREW_LETTER
+      }
+      return WB_ALETTER
+  }
+  return WB_OTHER
+}
+
+func IsAHLetter(property int) bool {
+  return property == WB_ALETTER || property == WB_HEBREW_LETTER
+}
+
+func IsMidLetterQ(property int) bool {
+  return property == WB_MID_LETTER || property == WB_MID_NUM_LET || property == WB_SINGLE_QUOTE
+}
+
+func IsMidNumQ(property int) bool {
+  return property == WB_MID_NUM || property == WB_MID_NUM_LET || property == WB_SINGLE_QUOTE
+}
+
+func IsIgnorable(property int) bool {
+  return property == WB_EXTEND || property == WB_FORMAT || property == WB_ZWJ
+}
+
+// split into words and punctuation; whitespace is dropped
+func Tokenize(str string) []Token {
+  runes := make([]rune, 0, len(str))
+  offsets := make([]int, 0, len(str) + 1)
+  properties := make([]int, 0, len(str))
+  for offset, item := range str {
+    runes = append(runes, item)
+    offsets = append(offsets, offset)
+    properties = append(properties, WordBreakProperty(item))
+  }
+  offsets = append(offsets, len(str))
+
+  // previous / next property skipping extend and format characters (WB4)
+  before := func(i int) (int, int) {
+    for i--; i >= 0; i-- {
+      if !IsIgnorable(properties[i]) || i == 0 {
+        return properties[i], i
+      }
+    }
+    return WB_OTHER, -1
+  }
+  after := func(i int) int {
+    for i++; i < len(properties); i++ {
+      if !IsIgnorable(properties[i]) {
+        return properties[i]
+      }
+    }
+    return WB_OTHER
+  }
+
+  isBreak := func(i int) bool {
+    previous := properties[i - 1]
+    current := properties[i]
+    switch {
+      case previous == WB_CR && current == WB_LF:
+        return false
+      case previous == WB_CR || previous == WB_LF || previous == WB_NEWLINE:
+        return true
+      case current == WB_CR || current == WB_LF || current == WB_NEWLINE:
+        return true
+      case previous == WB_WSEG_SPACE && current == WB_WSEG_SPACE:
+        return false
+      case IsIgnorable(current):
+        return false
+    }
+    previous, p := before(i)
+    beforePrevious := WB_OTHER
+    if p > 0 {
+      beforePrevious, _ = before(p)
+    }
+    next := after(i)
+    switch {
+      case IsAHLetter(previous) && IsAHLetter(current):
+        return false
+      case IsAHLetter(previous) && IsMidLetterQ(current) && IsAHLetter(next):
+        return false
+      case IsAHLetter(beforePrevious) && IsMidLetterQ(previous) && IsAHLetter(current):
+        return false
+      case previous == WB_HEBREW_LETTER && current == WB_SINGLE_QUOTE:
+        return false
+      case previous == WB_HEBREW_LETTER && current == WB_DOUBLE_QUOTE && next == WB_HEBREW_LETTER:
+        return false
+      case beforePrevious == WB_HEBREW_LETTER && previous == WB_DOUBLE_QUOTE && current == WB_HEBREW_LETTER:
+        return false
+      case previous == WB_NUMERIC && current == WB_NUMERIC:
+        return false
+      case IsAHLetter(previous) && current == WB_NUMERIC:
+        return false
+      case previous == WB_NUMERIC && IsAHLetter(current):
+        return false
+      case beforePrevious == WB_NUMERIC && IsMidNumQ(previous) && current == WB_NUMERIC:
+        return false
+      case previous == WB_NUMERIC && IsMidNumQ(current) && next == WB_NUMERIC:
+        return false
+      case previous == WB_KATAKANA && current == WB_KATAKANA:
+        return false
+      case (IsAHLetter(previous) || previous == WB_NUMERIC || previous == WB_KATAKANA || previous == WB_EXTEND_NUM_LET) && current == WB_EXTEND_NUM_LET:
+        return false
+      case previous == WB_EXTEND_NUM_LET && (IsAHLetter(current) || current == WB_NUMERIC || current == WB_KATAKANA):
+        return false
+    }
+    return true
+  }
+
+  tokens := make([]Token, 0, len(runes) / 4 + 1)
+  start := 0
+  for i := 1; i <= len(runes); i++ {
+    if i == len(runes) || isBreak(i) {
+      text := str[offsets[start]:offsets[i]]
+      if !IsSpace(text) {
+        tokens = append(tokens, Token{Text: text, Start: offsets[start], End: offsets[i]})
+      }
+      start = i
+    }
+  }
+  return tokens
+}
+
+func IsSpace(str string) bool {
+  for _, item := range str {
+    if !unicode.IsSpace(item) && !IsIgnorable(WordBreakProperty(item)) {
+      return false
+    }
+  }
+  return true
+}
+
+// words contain at least one letter or digit; everything else is punctuation
+func IsWord(str string) bool {
+  for _, item := range str {
+    if unicode.IsLetter(item) || unicode.IsNumber(item) {
+      return true
+    }
+  }
+  return false
+}
+
+func FirstRune(str string) rune {
+  item, _ := utf8.DecodeRuneInString(str)
+  return item
+}
@@ -0,0 +1,335 @@
+package index
+
+import (
+  "fmt"
+  "math"
+  "sort"
+  "strconv"
+)
+
+// collections with more vectors than this get an inverted file index
+const VECTOR_IVF_THRESHOLD int = 10000
+const VECTOR_IVF_ITERATIONS int = 10
+const VECTOR_IVF_SAMPLE int = 50 // training vectors per list
+
+const KNN_DEFAULT_K int = 10
+
+var VECTOR_METRICS = []string{"cosine", "dot", "l2"}
+
+// vectors of one field by item; nil when missing
+type VectorIndex struct {
+  Dimensions int
+  Vectors [][]float64
+  Count int
+
+  // approximate search by metric; nil for small collections
+  IVF map[string]*VectorLists
+}
+
+// k-means lists probed by l2 distance; for cosine the vectors are
+// normalised first, so the nearest by l2 are the nearest by angle. dot has
+// no lists and is always exact.
+type VectorLists struct {
+  Normalised bool
+  Centroids [][]float64
+  Lists [][]int
+}
+
+// nearest neighbour query; either a vector or the record to take one from
+type KnnQuery struct {
+  Field string `json:"field"`
+  Vector []float64 `json:"vector,omitempty"`
+  SimilarTo * int `json:"similar_to,omitempty"`
+  K int `json:"k"`
+  Metric string `json:"metric"`
+}
+
+func VectorAccessor (field SchemaField) func(int) ([]float64, bool) {
+  uniqueValues := field.UniqueValues
+  valueIndex := field.ValueIndex
+  return func(x int) ([]float64, bool) {
+    xx := valueIndex[x]
+    if xx != -1 {
+      return uniqueValues[xx].([]float64), true
+    } else {
+      return nil, false
+    }
+  }
+}
+
+// larger is more similar for every metric
+func VectorSimilarity(metric string) func([]float64, []float64) float64 {
+  switch metric {
+    case "cosine":
+      return func(a, b []float64) float64 {
+        dot, aa, bb := 0.0, 0.0, 0.0
+        for i, x := range a {
+          dot += x * b[i]
+          aa += x * x
+          bb += b[i] * b[i]
+        }
+        if aa == 0 || bb == 0 {
+          return 0
+        }
+        return dot / math.Sqrt(aa * bb)
+      }
+    case "dot":
+      return func(a, b []float64) float64 {
+        dot := 0.0
+        for i, x := range a {
+          dot += x * b[i]
+        }
+        return dot
+      }
+    case "l2":
+      return func(a, b []float64) float64 {
+        return 1 / (1 + math.Sqrt(SquaredDistance(a, b)))
+      }
+  }
+  return nil
+}
+
+func SquaredDistance(a, b []float64) float64 {
+  distance := 0.0
+  for i, x := range a {
+    distance += (x - b[i]) * (x - b[i])
+  }
+  return distance
+}
+
+func IndexVectorFields(schema * Schema, search * Search) {
+  for field, fieldData := range schema.Properties {
+    if fieldData.Type != "vector" || !search.Searchable(field) {
+      continue
+    }
+    fmt.Print("Bootstrapping search... ", field, " vector");
+    vectorIndex := &VectorIndex{Dimensions: fieldData.Dimensions, Vectors: make([][]float64, schema.TotalItems)}
+    getVector := VectorAccessor(fieldData)
+    for i := 0; i < schema.TotalItems; i++ {
+      if vector, has := getVector(i); has {
+        vectorIndex.Vectors[i] = vector
+        vectorIndex.Count++
+      }
+    }
+    if vectorIndex.Count > VECTOR_IVF_THRESHOLD {
+      lists := int(math.Sqrt(float64(vectorIndex.Count)))
+      vectorIndex.IVF = map[string]*VectorLists{"l2": vectorIndex.BuildLists(lists, false), "cosine": vectorIndex.BuildLists(lists, true)}
+    }
+    if search.VectorIndexes == nil {
+      search.VectorIndexes = make(map[string]*VectorIndex)
+    }
+    search.VectorIndexes[field] = vectorIndex
+    search.Fields[field] = SearchField{Dimensions: fieldData.Dimensions, Metrics: VECTOR_METRICS, Entropy: fieldData.Entropy}
+    fmt.Println(";");
+  }
+}
+
+// unit length copy; zero vectors stay zero
+func Normalise(vector []float64) []float64 {
+  length := 0.0
+  for _, x := range vector {
+    length += x * x
+  }
+  output := make([]float64, len(vector))
+  if length > 0 {
+    length = math.Sqrt(length)
+    for d, x := range vector {
+      output[d] = x / length
+    }
+  }
+  return output
+}
+
+// k-means over a sample of vectors, then every vector goes to its nearest list
+func (vectorIndex * VectorIndex) BuildLists(lists int, normalised bool) * VectorLists {
+  items := make([]int, 0, vectorIndex.Count)
+  vectors := make([][]float64, len(vectorIndex.Vectors))
+  for i, vector := range vectorIndex.Vectors {
+    if vector != nil {
+      items = append(items, i)
+      vectors[i] = vector
+      if normalised {
+        vectors[i] = Normalise(vector)
+      }
+    }
+  }
+  sample := items
+  if len(items) > lists * VECTOR_IVF_SAMPLE {
+    sample = make([]int, lists * VECTOR_IVF_SAMPLE)
+    for i := range sample {
+      sample[i] = items[i * len(items) / len(sample)]
+    }
+  }
+
+  centroids := make([][]float64, lists)
+  for c := range centroids {
+    centroids[c] = append([]float64(nil), vectors[sample[c * len(sample) / lists]]...)
+  }
+  assigned := make([]int, len(sample))
+  for iteration := 0; iteration < VECTOR_IVF_ITERATIONS; iteration++ {
+    for s, item := range sample {
+      assigned[s] = NearestCentroid(centroids, vectors[item])
+    }
+    sums := make([][]float64, lists)
+    counts := make([]int, lists)
+    for s, item := range sample {
+      c := assigned[s]
+      if sums[c] == nil {
+        sums[c] = make([]float64, vectorIndex.Dimensions)
+      }
+      for d, x := range vectors[item] {
+        sums[c][d] += x
+      }
+      counts[c]++
+    }
+    for c := range centroids {
+      // empty lists keep their centroid
+      if counts[c] > 0 {
+        for d := range centroids[c] {
+          centroids[c][d] = sums[c][d] / float64(counts[c])
+        }
+      }
+    }
+  }
+
+  output := &VectorLists{Normalised: normalised, Centroids: centroids, Lists: make([][]int, lists)}
+  for _, item := range items {
+    c := NearestCentroid(centroids, vectors[item])
+    output.Lists[c] = append(output.Lists[c], item)
+  }
+  return output
+}
+
+func NearestCentroid(centroids [][]float64, vector []float64) int {
+  best := 0
+  bestDistance := math.Inf(1)
+  for c, centroid := range centroids {
+    if distance := SquaredDistance(centroid, vector); distance < bestDistance {
+      best = c
+      bestDistance = distance
+    }
+  }
+  return best
+}
+
+// records in the lists nearest the query vector
+func (vectorIndex * VectorIndex) Candidates(vector []float64, ivf * VectorLists) []bool {
+  if ivf.Normalised {
+    vector = Normalise(vector)
+  }
+  lists := make(SearchResults, len(ivf.Centroids))
+  for c, centroid := range ivf.Centroids {
+    lists[c] = SearchResult{Item: c, Score: -SquaredDistance(vector, centroid)}
+  }
+  sort.Sort(lists)
+  probes := int(math.Sqrt(float64(len(lists)))) + 1
+  if probes > len(lists) {
+    probes = len(lists)
+  }
+  candidates := make([]bool, len(vectorIndex.Vectors))
+  for _, list := range lists[:probes] {
+    for _, item := range ivf.Lists[list.Item] {
+      candidates[item] = true
+    }
+  }
+  return candidates
+}
+
+// knn query from similar_to, vector_field, k and metric parameters
+func ParseKnnQuery(queries map[string][]string) (* KnnQuery, []string) {
+  value, has := queries["similar_to"]
+  if !has {
+    return nil, nil
+  }
+  errors := make([]string, 0)
+  query := &KnnQuery{}
+  if item, err := strconv.Atoi(value[0]); err == nil {
+    query.SimilarTo = &item
+  } else {
+    return nil, []string{"similar_to '" + value[0] + "' is not a record id"}
+  }
+  if value, has := queries["vector_field"]; has {
+    query.Field = value[0]
+  }
+  if value, has := queries["k"]; has {
+    if k, err := strconv.Atoi(value[0]); err == nil {
+      query.K = k
+    } else {
+      errors = append(errors, "k '" + value[0] + "' is not a number")
+    }
+  }
+  if value, has := queries["metric"]; has {
+    query.Metric = value[0]
+  }
+  return query, errors
+}
+
+// k nearest records among results; scores are similarities
+func (search * Search) SearchVector(query * KnnQuery, schema * Schema, input SearchResults) (SearchResults, []string) {
+
+  if query.Field == "" && len(search.VectorIndexes) == 1 {
+    for field, _ := range search.VectorIndexes {
+      query.Field = field
+    }
+  }
+  vectorIndex, has := search.VectorIndexes[query.Field]
+  if !has {
+    return input[:0], []string{"vector field '" + query.Field + "' does not exist"}
+  }
+  if query.Metric == "" {
+    query.Metric = "cosine"
+  }
+  similarity := VectorSimilarity(query.Metric)
+  if similarity == nil {
+    return input[:0], []string{"metric '" + query.Metric + "' is not supported"}
+  }
+  if query.K <= 0 {
+    query.K = KNN_DEFAULT_K
+  }
+  vector := query.Vector
+  exclude := -1
+  if query.SimilarTo != nil {
+    exclude = *query.SimilarTo
+    if exclude < 0 || exclude >= schema.TotalItems || vectorIndex.Vectors[exclude] == nil {
+      return input[:0], []string{"record " + fmt.Sprint(exclude) + " has no '" + query.Field + "' vector"}
+    }
+    vector = vectorIndex.Vectors[exclude]
+  }
+  if len(vector) != vectorIndex.Dimensions {
+    return input[:0], []string{"vector needs " + fmt.Sprint(vectorIndex.Dimensions) + " dimensions"}
+  }
+
+  // approximate only when the filters leave more records than probing visits
+  var candidates []bool
+  if ivf, has := vectorIndex.IVF[query.Metric]; has && len(input) > vectorIndex.Count / int(math.Sqrt(float64(len(ivf.Lists)))) {
+    candidates = vectorIndex.Candidates(vector, ivf)
+  }
+
+  if candidates == nil {
+    return vectorIndex.Nearest(vector, similarity, query.K, exclude, nil, input), nil
+  }
+  results := make(SearchResults, len(input))
+  copy(results, input)
+  output := vectorIndex.Nearest(vector, similarity, query.K, exclude, candidates, results)
+  if len(output) < query.K {
+    // too few candidates pass the filters
+    output = vectorIndex.Nearest(vector, similarity, query.K, exclude, nil, input)
+  }
+  return output, nil
+}
+
+func (vectorIndex * VectorIndex) Nearest(vector []float64, similarity func([]float64, []float64) float64, k int, exclude int, candidates []bool, input SearchResults) SearchResults {
+  output := input[:0]
+  for _, x := range input {
+    if x.Item == exclude || vectorIndex.Vectors[x.Item] == nil || (candidates != nil && !candidates[x.Item]) {
+      continue
+    }
+    x.Score = similarity(vector, vectorIndex.Vectors[x.Item])
+    output = append(output, x)
+  }
+  sort.Sort(output)
+  if len(output) > k {
+    output = output[:k]
+  }
+  return output
+}
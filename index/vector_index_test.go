@@ -0,0 +1,130 @@
+package index
+
+import (
+  "math/rand"
+  "reflect"
+  "testing"
+)
+
+func TestVectorDimensions(t *testing.T) {
+  tests := []struct {
+    data []interface{}
+    want int
+  }{
+    {[]interface{}{[]interface{}{1.0, 2.0, 3.0}, nil, []interface{}{0.0, 0.5, -1.0}}, 3},
+    {[]interface{}{[]interface{}{1.0, 2.0}}, 2},
+    // one number is an array, not a vector
+    {[]interface{}{[]interface{}{1.0}}, 0},
+    {[]interface{}{[]interface{}{1.0, 2.0}, []interface{}{1.0, 2.0, 3.0}}, 0},
+    {[]interface{}{[]interface{}{1.0, "2"}}, 0},
+    {[]interface{}{[]interface{}{1.0, 2.0}, "x"}, 0},
+    {[]interface{}{nil}, 0},
+  }
+  for _, test := range tests {
+    if got := VectorDimensions(&test.data); got != test.want {
+      t.Errorf("VectorDimensions(%v) = %d, want %d", test.data, got, test.want)
+    }
+  }
+}
+
+// 3000 vectors around 30 directions, with lengths varying a hundredfold so
+// the nearest by angle are not the nearest by distance
+func VectorFixture() (* Search, * Schema, [][]float64) {
+  random := rand.New(rand.NewSource(1))
+  dimensions := 8
+  directions := make([][]float64, 30)
+  for c := range directions {
+    directions[c] = make([]float64, dimensions)
+    for d := range directions[c] {
+      directions[c][d] = random.NormFloat64()
+    }
+  }
+  vectorIndex := &VectorIndex{Dimensions: dimensions, Vectors: make([][]float64, 3000)}
+  for i := range vectorIndex.Vectors {
+    scale := 0.1 + 10 * random.Float64()
+    vector := make([]float64, dimensions)
+    for d, x := range directions[i % len(directions)] {
+      vector[d] = scale * (x + 0.3 * random.NormFloat64())
+    }
+    vectorIndex.Vectors[i] = vector
+    vectorIndex.Count++
+  }
+  vectorIndex.IVF = map[string]*VectorLists{"l2": vectorIndex.BuildLists(54, false), "cosine": vectorIndex.BuildLists(54, true)}
+  queries := make([][]float64, 50)
+  for q := range queries {
+    queries[q] = make([]float64, dimensions)
+    for d, x := range directions[q % len(directions)] {
+      queries[q][d] = (0.1 + 10 * random.Float64()) * (x + 0.3 * random.NormFloat64())
+    }
+  }
+  search := &Search{VectorIndexes: map[string]*VectorIndex{"embedding": vectorIndex}}
+  return search, &Schema{TotalItems: len(vectorIndex.Vectors)}, queries
+}
+
+// the approximate index finds most of the exact nearest records
+func TestVectorRecall(t *testing.T) {
+  search, schema, queries := VectorFixture()
+  vectorIndex := search.VectorIndexes["embedding"]
+  for _, metric := range VECTOR_METRICS {
+    found, total := 0, 0
+    for _, vector := range queries {
+      exact := make(map[int]bool)
+      for _, x := range vectorIndex.Nearest(vector, VectorSimilarity(metric), 10, -1, nil, SearchStart(schema)) {
+        exact[x.Item] = true
+      }
+      results, errors := search.SearchVector(&KnnQuery{Field: "embedding", Vector: vector, Metric: metric}, schema, SearchStart(schema))
+      if len(errors) > 0 || len(results) != 10 {
+        t.Fatalf("%s: %d results, errors %v", metric, len(results), errors)
+      }
+      for _, x := range results {
+        if exact[x.Item] {
+          found++
+        }
+      }
+      total += len(exact)
+    }
+    if recall := float64(found) / float64(total); recall < 0.9 {
+      t.Errorf("%s recall = %v, want at least 0.9", metric, recall)
+    } else if metric == "dot" && recall != 1 {
+      t.Errorf("dot recall = %v, want 1 as it is exact", recall)
+    }
+  }
+}
+
+// when the probed lists hold fewer than k filtered records the search is exact
+func TestVectorFewCandidates(t *testing.T) {
+  search, schema, _ := VectorFixture()
+  vectorIndex := search.VectorIndexes["embedding"]
+  // the longest vector of the first direction, far from most records
+  query := vectorIndex.Vectors[0]
+  for item := 30; item < len(vectorIndex.Vectors); item += 30 {
+    if SquaredDistance(vectorIndex.Vectors[item], make([]float64, 8)) > SquaredDistance(query, make([]float64, 8)) {
+      query = vectorIndex.Vectors[item]
+    }
+  }
+  // 500 records of five directions other than that of the query pass the
+  // filters; more than probing visits, so the approximate index is used
+  input := make(SearchResults, 0)
+  for _, x := range SearchStart(schema) {
+    if direction := x.Item % 30; direction >= 10 && direction < 15 {
+      input = append(input, x)
+    }
+  }
+  for _, metric := range []string{"cosine", "l2"} {
+    candidates := vectorIndex.Candidates(query, vectorIndex.IVF[metric])
+    passed := 0
+    for _, x := range input {
+      if candidates[x.Item] {
+        passed++
+      }
+    }
+    if passed >= 10 {
+      t.Fatalf("%s: %d filtered records are candidates, the test needs fewer than 10", metric, passed)
+    }
+    exact := vectorIndex.Nearest(query, VectorSimilarity(metric), 10, -1, nil, append(SearchResults(nil), input...))
+    results, errors := search.SearchVector(&KnnQuery{Field: "embedding", Vector: query, Metric: metric}, schema, append(SearchResults(nil), input...))
+    if len(errors) > 0 || !reflect.DeepEqual(results, exact) {
+      t.Errorf("%s: %v, errors %v, want %v", metric, results, errors, exact)
+    }
+  }
+}
@@ -6,6 +6,7 @@ rm -rf bin
 mkdir bin
 
 go get github.com/reiver/go-porterstemmer
+go get github.com/kljensen/snowball
 
 for app in restapi
 do
@@ -1,8 +1,13 @@
 package main
 
 import (
+  "encoding/json"
   "flag"
+  "fmt"
   "os"
+  "strconv"
+  "strings"
+  "time"
   "github.com/nahidakbar/go-restapi/input"
   "github.com/nahidakbar/go-restapi/index"
   "github.com/nahidakbar/go-restapi/api"
@@ -11,12 +16,108 @@ import (
 var address = flag.String("address", ":8080", "")
 var datafile = flag.String("datafile", "", "")
 var path = flag.String("path", "/", "")
+var foldCase = flag.Bool("foldcase", true, "")
+var foldAccents = flag.Bool("foldaccents", false, "")
+var language = flag.String("language", "english", "")
+var fieldLanguages = flag.String("fieldlanguages", "", "")
+var languageField = flag.String("languagefield", "", "")
+var textWeights = flag.String("textweights", "", "")
+var highlightPre = flag.String("highlightpre", "<em>", "")
+var highlightPost = flag.String("highlightpost", "</em>", "")
+var synonyms = flag.String("synonyms", "", "")
+var duplicateThreshold = flag.Float64("duplicatethreshold", 0.8, "")
+var sql = flag.String("sql", "", "")
+var include = flag.String("include", "", "")
+var exclude = flag.String("exclude", "", "")
+var summary = flag.String("summary", "", "")
+var fields = flag.String("fields", "", "")
+var maxLimit = flag.Int("maxlimit", 100, "")
+var cursors = flag.Int("cursors", 256, "")
+var cursorTTL = flag.Duration("cursorttl", 10 * time.Minute, "")
+var cursorWindow = flag.Int("cursorwindow", 10000, "")
+
+// field,field
+func ParseFieldList(str string) []string {
+  output := make([]string, 0)
+  for _, field := range strings.Split(str, ",") {
+    if field = strings.TrimSpace(field); len(field) > 0 {
+      output = append(output, field)
+    }
+  }
+  return output
+}
+
+// field:value,field:value
+func ParseFieldMap(str string) map[string]string {
+  output := make(map[string]string)
+  for _, pair := range strings.Split(str, ",") {
+    if i := strings.LastIndex(pair, ":"); i != -1 {
+      output[strings.TrimSpace(pair[:i])] = strings.TrimSpace(pair[i+1:])
+    }
+  }
+  return output
+}
 
 func main() {
   flag.Parse()
   if len(*datafile) > 0 {
+    options := index.Options{
+      FoldCase: *foldCase,
+      FoldAccents: *foldAccents,
+      Language: *language,
+      FieldLanguages: ParseFieldMap(*fieldLanguages),
+      LanguageField: *languageField,
+      HighlightPre: *highlightPre,
+      HighlightPost: *highlightPost,
+      DuplicateThreshold: *duplicateThreshold,
+      IncludeFields: ParseFieldList(*include),
+      ExcludeFields: ParseFieldList(*exclude),
+      SummaryFields: ParseFieldList(*summary),
+      Fields: *fields,
+      MaxLimit: *maxLimit,
+      CursorCacheSize: *cursors,
+      CursorTTL: *cursorTTL,
+      CursorWindow: *cursorWindow,
+    }
+    languages := []string{options.Language}
+    for _, name := range options.FieldLanguages {
+      languages = append(languages, name)
+    }
+    for _, name := range languages {
+      if index.LookupLanguage(name) == nil {
+        fmt.Println("Unsupported language", name)
+        os.Exit(2)
+      }
+    }
+    options.TextWeights = make(map[string]float64)
+    for field, value := range ParseFieldMap(*textWeights) {
+      weight, err := strconv.ParseFloat(value, 64)
+      if err != nil {
+        fmt.Println("Invalid weight", field, value)
+        os.Exit(2)
+      }
+      options.TextWeights[field] = weight
+    }
+    if len(*synonyms) > 0 {
+      var err error
+      if options.Synonyms, err = index.LoadSynonyms(*synonyms); err != nil {
+        fmt.Println("Synonyms", err)
+        os.Exit(1)
+      }
+    }
     if data, err := input.Load(*datafile); err == nil {
-      os.Exit(api.Serve(index.Index(data), *address, *path))
+      collection := index.Index(data, options)
+      if len(*sql) > 0 {
+        collection.Wait()
+        output := collection.SQL(*sql).(map[string]interface{})
+        js, _ := json.MarshalIndent(output, "", "  ")
+        fmt.Println(string(js))
+        if _, failed := output["errors"]; failed {
+          os.Exit(1)
+        }
+        os.Exit(0)
+      }
+      os.Exit(api.Serve(collection, *address, *path))
     } else {
       os.Exit(1)
     }